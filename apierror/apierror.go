@@ -0,0 +1,164 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"main.go/logging"
+	"main.go/repository"
+)
+
+// Identifica o tipo do erro no JSON, estável para os clientes compararem
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeTooLarge         Code = "request_too_large"
+	CodeValidation       Code = "validation_failed"
+	CodeTooManyRequests  Code = "too_many_requests"
+	CodeInternal         Code = "internal_error"
+	CodeTimeout          Code = "timeout"
+)
+
+var statusByCode = map[Code]int{
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	CodeConflict:         http.StatusConflict,
+	CodeTooLarge:         http.StatusRequestEntityTooLarge,
+	CodeValidation:       http.StatusUnprocessableEntity,
+	CodeTooManyRequests:  http.StatusTooManyRequests,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeTimeout:          http.StatusGatewayTimeout,
+}
+
+// Erro que pode ser mostrado ao cliente. Cause fica só no log
+type Error struct {
+	Code    Code
+	Message string
+	Details any
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func (e *Error) Status() int {
+	if status, ok := statusByCode[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Corpo malformado ou parâmetro que não dá para interpretar
+func BadRequest(message string) *Error {
+	return &Error{Code: CodeBadRequest, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Code: CodeUnauthorized, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Code: CodeForbidden, Message: message}
+}
+
+func NotFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func MethodNotAllowed(message string) *Error {
+	return &Error{Code: CodeMethodNotAllowed, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Code: CodeConflict, Message: message}
+}
+
+// Corpo maior que o limite da rota
+func TooLarge(message string) *Error {
+	return &Error{Code: CodeTooLarge, Message: message}
+}
+
+// Requisição bem formada com valores inválidos. details descreve o que está errado
+func Validation(message string, details any) *Error {
+	return &Error{Code: CodeValidation, Message: message, Details: details}
+}
+
+func TooManyRequests(message string) *Error {
+	return &Error{Code: CodeTooManyRequests, Message: message}
+}
+
+// Falha do servidor. A mensagem vai para o cliente e cause para o log
+func Internal(message string, cause error) *Error {
+	return &Error{Code: CodeInternal, Message: message, Cause: cause}
+}
+
+// Status não padrão (do nginx) para requisições que o cliente abandonou. Só aparece
+// no log de acesso e nas métricas, ninguém recebe a resposta
+const StatusClientClosedRequest = 499
+
+type body struct {
+	Code      Code   `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details"`
+	RequestID string `json:"request_id"`
+}
+
+// Responde err no formato {code, message, details, request_id}. Além de *Error entende
+// os erros dos repositórios e do contexto: query que estourou o tempo vira 504 e, se o
+// cliente desconectou, não há para quem responder: só registra e marca a resposta
+// com StatusClientClosedRequest
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+
+	var apiErr *Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		slog.InfoContext(ctx, "request aborted, client canceled", "method", r.Method, "path", r.URL.Path)
+		w.WriteHeader(StatusClientClosedRequest)
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		apiErr = &Error{Code: CodeTimeout, Message: "Database timeout", Cause: err}
+	case errors.As(err, &apiErr):
+	case errors.Is(err, repository.ErrNotFound):
+		apiErr = NotFound("Not found")
+	case errors.Is(err, repository.ErrConflict):
+		apiErr = Conflict("Conflict")
+	default:
+		apiErr = Internal("Internal server error", err)
+	}
+
+	status := apiErr.Status()
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, apiErr.Message, "status", status, "error", apiErr.Cause)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: logging.RequestIDFrom(ctx),
+	})
+}
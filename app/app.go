@@ -1,7 +1,35 @@
 package app
 
-import "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"main.go/auth"
+	"main.go/config"
+	"main.go/mail"
+	"main.go/ratelimit"
+	"main.go/repository"
+)
 
 type App struct {
-	DB neo4j.DriverWithContext
+	Config config.Config
+
+	// acesso aos dados: Users, Posts, Sessions, AccountTokens e Health
+	repository.Repositories
+
+	Tokens *auth.TokenManager
+	TOTP   *auth.TOTP
+	// tentativas de login falhas, por email e por IP
+	LoginGuard  *auth.LoginGuard
+	RateLimiter ratelimit.Store
+	Passwords   auth.PasswordSettings
+	Mailer      mail.Mailer
+	// emails enviados em segundo plano, esperados antes de fechar o Mailer
+	Mails sync.WaitGroup
+
+	// exibido em /version
+	StartedAt time.Time
+	// ligado ao receber SIGTERM: /readyz passa a falhar enquanto as requisições terminam
+	Draining atomic.Bool
 }
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type GuardPolicy struct {
+	// falhas toleradas antes de começar a atrasar novas tentativas
+	FreeAttempts int
+	// atraso após a primeira falha além das toleradas, dobrado a cada nova falha
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// a partir de quantas falhas a chave fica bloqueada por Lockout
+	MaxFailures int
+	Lockout     time.Duration
+	// sem falhas por esse tempo, o contador é zerado
+	Window time.Duration
+}
+
+var (
+	DefaultEmailGuardPolicy = GuardPolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		MaxFailures:  10,
+		Lockout:      15 * time.Minute,
+		Window:       15 * time.Minute,
+	}
+	// mais tolerante, vários usuários podem compartilhar o mesmo IP
+	DefaultIPGuardPolicy = GuardPolicy{
+		FreeAttempts: 10,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		MaxFailures:  50,
+		Lockout:      15 * time.Minute,
+		Window:       time.Hour,
+	}
+)
+
+type attempts struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+type tracker struct {
+	policy  GuardPolicy
+	entries map[string]*attempts
+}
+
+func (t *tracker) retryAfter(key string, now time.Time) time.Duration {
+	entry, ok := t.entries[key]
+	if !ok || !now.Before(entry.blockedUntil) {
+		return 0
+	}
+	return entry.blockedUntil.Sub(now)
+}
+
+func (t *tracker) fail(key string, now time.Time) {
+	entry, ok := t.entries[key]
+	if !ok || now.Sub(entry.lastFailure) > t.policy.Window {
+		entry = &attempts{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+
+	switch {
+	case entry.failures >= t.policy.MaxFailures:
+		entry.blockedUntil = now.Add(t.policy.Lockout)
+	case entry.failures > t.policy.FreeAttempts:
+		delay := t.policy.BaseDelay << (entry.failures - t.policy.FreeAttempts - 1)
+		if delay <= 0 || delay > t.policy.MaxDelay {
+			delay = t.policy.MaxDelay
+		}
+		entry.blockedUntil = now.Add(delay)
+	}
+}
+
+func (t *tracker) prune(now time.Time) {
+	for key, entry := range t.entries {
+		if now.Sub(entry.lastFailure) > t.policy.Window && !now.Before(entry.blockedUntil) {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// Controla tentativas de login falhas por email e por IP, em memória
+type LoginGuard struct {
+	mu        sync.Mutex
+	clock     Clock
+	email     *tracker
+	ip        *tracker
+	lastPrune time.Time
+}
+
+func NewLoginGuard(emailPolicy GuardPolicy, ipPolicy GuardPolicy, clock Clock) *LoginGuard {
+	return &LoginGuard{
+		clock: clock,
+		email: &tracker{policy: emailPolicy, entries: map[string]*attempts{}},
+		ip:    &tracker{policy: ipPolicy, entries: map[string]*attempts{}},
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Retorna quanto tempo falta para uma nova tentativa ser aceita, ou 0 se já pode tentar
+func (g *LoginGuard) RetryAfter(email string, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	return max(g.email.retryAfter(normalizeEmail(email), now), g.ip.retryAfter(ip, now))
+}
+
+func (g *LoginGuard) Failure(email string, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	g.email.fail(normalizeEmail(email), now)
+	g.ip.fail(ip, now)
+
+	// limpa entradas antigas de tempos em tempos para o mapa não crescer sem limite
+	if now.Sub(g.lastPrune) > time.Minute {
+		g.email.prune(now)
+		g.ip.prune(now)
+		g.lastPrune = now
+	}
+}
+
+// Zera o contador do email. O do IP só expira com o tempo
+func (g *LoginGuard) Success(email string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.email.entries, normalizeEmail(email))
+}
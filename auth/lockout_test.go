@@ -0,0 +1,109 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"main.go/auth"
+)
+
+var testGuardPolicy = auth.GuardPolicy{
+	FreeAttempts: 2,
+	BaseDelay:    time.Second,
+	MaxDelay:     4 * time.Second,
+	MaxFailures:  8,
+	Lockout:      15 * time.Minute,
+	Window:       15 * time.Minute,
+}
+
+// O IP não interfere: limites altos o bastante para nunca bloquear nos testes
+var looseIPPolicy = auth.GuardPolicy{FreeAttempts: 1000, MaxFailures: 1000, Window: time.Hour}
+
+const (
+	guardEmail = "alice@example.com"
+	guardIP    = "203.0.113.7"
+)
+
+func TestLoginGuardBacksOffExponentially(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	guard := auth.NewLoginGuard(testGuardPolicy, looseIPPolicy, clock)
+
+	// as falhas toleradas não atrasam; depois o atraso dobra até MaxDelay
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		guard.Failure(guardEmail, guardIP)
+		if got := guard.RetryAfter(guardEmail, guardIP); got != want {
+			t.Errorf("after failure %d: retry after = %s, want %s", i+1, got, want)
+		}
+	}
+
+	clock.now = clock.now.Add(4 * time.Second)
+	if got := guard.RetryAfter(guardEmail, guardIP); got != 0 {
+		t.Errorf("retry after once the delay passed = %s, want 0", got)
+	}
+
+	// o email é comparado sem diferenciar maiúsculas e espaços
+	guard.Failure(" Alice@Example.com ", guardIP)
+	if got := guard.RetryAfter(guardEmail, guardIP); got != 4*time.Second {
+		t.Errorf("retry after for the same email in other case = %s, want 4s", got)
+	}
+}
+
+func TestLoginGuardLocksOutAfterMaxFailures(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	guard := auth.NewLoginGuard(testGuardPolicy, looseIPPolicy, clock)
+
+	for range testGuardPolicy.MaxFailures {
+		guard.Failure(guardEmail, guardIP)
+	}
+	if got := guard.RetryAfter(guardEmail, guardIP); got != testGuardPolicy.Lockout {
+		t.Fatalf("retry after = %s, want the lockout %s", got, testGuardPolicy.Lockout)
+	}
+
+	clock.now = clock.now.Add(testGuardPolicy.Lockout)
+	if got := guard.RetryAfter(guardEmail, guardIP); got != 0 {
+		t.Errorf("retry after the lockout = %s, want 0", got)
+	}
+}
+
+func TestLoginGuardResets(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	guard := auth.NewLoginGuard(testGuardPolicy, looseIPPolicy, clock)
+
+	fail := func(times int) {
+		for range times {
+			guard.Failure(guardEmail, guardIP)
+		}
+	}
+
+	// um login certo zera o contador do email
+	fail(testGuardPolicy.FreeAttempts)
+	guard.Success(guardEmail)
+	fail(testGuardPolicy.FreeAttempts)
+	if got := guard.RetryAfter(guardEmail, guardIP); got != 0 {
+		t.Errorf("retry after success and %d failures = %s, want 0", testGuardPolicy.FreeAttempts, got)
+	}
+
+	// sem falhas durante Window o contador também recomeça
+	clock.now = clock.now.Add(testGuardPolicy.Window + time.Second)
+	fail(testGuardPolicy.FreeAttempts)
+	if got := guard.RetryAfter(guardEmail, guardIP); got != 0 {
+		t.Errorf("retry after the window = %s, want 0", got)
+	}
+}
+
+func TestLoginGuardCountsFailuresByIP(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	guard := auth.NewLoginGuard(looseIPPolicy, testGuardPolicy, clock)
+
+	// emails diferentes, mesmo IP
+	guard.Failure("a@example.com", guardIP)
+	guard.Failure("b@example.com", guardIP)
+	guard.Failure("c@example.com", guardIP)
+
+	if got := guard.RetryAfter("d@example.com", guardIP); got != time.Second {
+		t.Errorf("retry after from the same IP = %s, want 1s", got)
+	}
+	if got := guard.RetryAfter("d@example.com", "198.51.100.1"); got != 0 {
+		t.Errorf("retry after from another IP = %s, want 0", got)
+	}
+}
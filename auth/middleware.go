@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"main.go/apierror"
+	"main.go/logging"
+)
+
+type ctxKey struct{}
+
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, ctxKey{}, claims)
+}
+
+// Retorna o id do usuário autenticado colocado no contexto por Authenticate
+func UserID(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(Claims)
+	return claims.UserID, ok
+}
+
+// Usuário autenticado e seu papel, usado nas checagens de Can
+func ActorFrom(ctx context.Context) (Actor, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(Claims)
+	return Actor{UserID: claims.UserID, Role: claims.Role}, ok
+}
+
+// Retorna a sessão (refresh token) a qual o access token pertence
+func SessionID(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(ctxKey{}).(Claims)
+	return claims.SessionID, ok && claims.SessionID != ""
+}
+
+// Consulta se a sessão de um access token ainda vale. Implementado pelo repositório de sessões
+type SessionChecker interface {
+	Active(ctx context.Context, userId string, sessionId string, now time.Time) (bool, error)
+}
+
+// Valida o token do header e a sessão a que ele pertence. Um token de sessão revogada
+// (logout, reuso do refresh token, troca de senha), expirada ou de conta apagada é recusado
+// mesmo antes de expirar
+func authenticate(ctx context.Context, tokens *TokenManager, sessions SessionChecker, header string) (Claims, error) {
+	tokenStr, found := strings.CutPrefix(header, "Bearer ")
+	if !found || tokenStr == "" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims, err := tokens.Parse(tokenStr)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	if claims.SessionID == "" {
+		return Claims{}, ErrInvalidToken
+	}
+	active, err := sessions.Active(ctx, claims.UserID, claims.SessionID, tokens.clock.Now())
+	if err != nil {
+		return Claims{}, err
+	}
+	if !active {
+		return Claims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// Middleware que exige um header "Authorization: Bearer <token>" válido
+func Authenticate(tokens *TokenManager, sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := authenticate(r.Context(), tokens, sessions, r.Header.Get("Authorization"))
+			if errors.Is(err, ErrInvalidToken) {
+				unauthorized(w, r)
+				return
+			}
+			if err != nil {
+				apierror.Write(w, r, err)
+				return
+			}
+			logging.SetUserID(r.Context(), claims.UserID)
+
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// Como Authenticate, mas deixa passar requisições anônimas. Útil em rotas públicas
+// cuja resposta muda quando o usuário está vendo os próprios dados
+func OptionalAuthenticate(tokens *TokenManager, sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if header := r.Header.Get("Authorization"); header != "" {
+				if claims, err := authenticate(r.Context(), tokens, sessions, header); err == nil {
+					logging.SetUserID(r.Context(), claims.UserID)
+					r = r.WithContext(WithClaims(r.Context(), claims))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	apierror.Write(w, r, apierror.Unauthorized("Unauthorized"))
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+	"main.go/config"
+)
+
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+type PasswordSettings struct {
+	// custo do bcrypt para novos hashes. Hashes com custo menor são refeitos no login
+	Cost   int
+	Policy PasswordPolicy
+	// hash com o mesmo custo, comparado no login de um email que não existe para que a
+	// resposta demore o mesmo que uma senha errada e não revele quais emails têm conta
+	DummyHash string
+}
+
+// Limites já validados pelo pacote config
+func InitPasswords(cfg config.PasswordConfig) PasswordSettings {
+	// só falha com custo fora do intervalo do bcrypt, o que a validação já impede
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), cfg.BcryptCost)
+
+	return PasswordSettings{
+		Cost:      cfg.BcryptCost,
+		DummyHash: string(dummyHash),
+		Policy: PasswordPolicy{
+			MinLength:     cfg.MinLength,
+			RequireUpper:  cfg.RequireUpper,
+			RequireLower:  cfg.RequireLower,
+			RequireDigit:  cfg.RequireDigit,
+			RequireSymbol: cfg.RequireSymbol,
+		},
+	}
+}
+
+// Todas as regras que a senha não cumpre, ex: "a digit"
+func (p PasswordPolicy) Problems(password string) []string {
+	var upper, lower, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			symbol = true
+		}
+	}
+
+	var problems []string
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("at least %d characters", p.MinLength))
+	}
+	// bcrypt ignora tudo depois de 72 bytes
+	if len(password) > 72 {
+		problems = append(problems, "at most 72 bytes")
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "a symbol")
+	}
+	return problems
+}
+
+// Diz se o hash foi gerado com custo menor que o configurado
+func (s PasswordSettings) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < s.Cost
+}
@@ -0,0 +1,72 @@
+package auth
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAdmin     Role = "admin"
+)
+
+// Converte o valor salvo no nó User. Usuários antigos sem role são tratados como "user"
+func ParseRole(value any) (Role, bool) {
+	str, _ := value.(string)
+	switch Role(str) {
+	case "", RoleUser:
+		return RoleUser, true
+	case RoleAdmin:
+		return RoleAdmin, true
+	}
+	return RoleUser, false
+}
+
+type Action string
+
+const (
+	ActionUpdateUser Action = "user:update"
+	ActionDeleteUser Action = "user:delete"
+	ActionChangeRole Action = "user:change-role"
+	// buscar um usuário pelo email. Liberado só para admin para não revelar quais emails têm conta
+	ActionLookupEmail Action = "user:lookup-email"
+	ActionDeletePost  Action = "post:delete"
+)
+
+type Actor struct {
+	UserID string
+	Role   Role
+}
+
+type rule struct {
+	// o dono do recurso pode executar a ação
+	owner bool
+	// papeis que podem executar a ação em recursos de qualquer usuário
+	roles []Role
+}
+
+var policies = map[Action]rule{
+	ActionUpdateUser:  {owner: true, roles: []Role{RoleAdmin}},
+	ActionDeleteUser:  {owner: true, roles: []Role{RoleAdmin}},
+	ActionChangeRole:  {owner: false, roles: []Role{RoleAdmin}},
+	ActionLookupEmail: {owner: false, roles: []Role{RoleAdmin}},
+	ActionDeletePost:  {owner: true, roles: []Role{RoleAdmin}},
+}
+
+// Diz se o ator pode executar a ação sobre um recurso pertencente a ownerId.
+// Ações sem política cadastrada são sempre negadas
+func Can(actor Actor, action Action, ownerId string) bool {
+	policy, ok := policies[action]
+	if !ok {
+		return false
+	}
+
+	if policy.owner && actor.UserID == ownerId {
+		return true
+	}
+
+	for _, role := range policy.roles {
+		if actor.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"main.go/config"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	purposeAccess = "access"
+	purposeMFA    = "mfa"
+
+	mfaChallengeTTL = 5 * time.Minute
+)
+
+type TokenManager struct {
+	secret     []byte
+	ttl        time.Duration
+	refreshTTL time.Duration
+	clock      Clock
+}
+
+type Claims struct {
+	UserID    string
+	SessionID string
+	Role      Role
+}
+
+type accessClaims struct {
+	Purpose   string `json:"typ"`
+	SessionID string `json:"sid,omitempty"`
+	Role      Role   `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func NewTokenManager(secret []byte, ttl time.Duration, refreshTTL time.Duration, clock Clock) *TokenManager {
+	return &TokenManager{secret: secret, ttl: ttl, refreshTTL: refreshTTL, clock: clock}
+}
+
+// JWT_SECRET é obrigatório para emitir tokens
+func InitTokens(cfg config.AuthConfig) (*TokenManager, error) {
+	if cfg.JWTSecret == "" {
+		return nil, errors.New("JWT_SECRET is not set")
+	}
+
+	return NewTokenManager([]byte(cfg.JWTSecret), cfg.JWTTTL, cfg.RefreshTTL, SystemClock{}), nil
+}
+
+func (m *TokenManager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// Gera um access token assinado (HS256) ligado à sessão do usuário.
+// Uma mudança de role só aparece no próximo token emitido
+func (m *TokenManager) Issue(userId string, sessionId string, role Role) (string, time.Time, error) {
+	return m.sign(accessClaims{Purpose: purposeAccess, SessionID: sessionId, Role: role}, userId, m.ttl)
+}
+
+// Token curto emitido quando a senha confere mas o usuário ainda precisa informar o código 2FA.
+// Não serve como access token
+func (m *TokenManager) IssueMFAChallenge(userId string) (string, time.Time, error) {
+	return m.sign(accessClaims{Purpose: purposeMFA}, userId, mfaChallengeTTL)
+}
+
+func (m *TokenManager) sign(claims accessClaims, userId string, ttl time.Duration) (string, time.Time, error) {
+	now := m.clock.Now()
+	expiresAt := now.Add(ttl)
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   userId,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (m *TokenManager) verify(tokenStr string, purpose string) (accessClaims, string, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (any, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.clock.Now),
+	)
+	if err != nil || claims.Purpose != purpose || claims.Subject == "" {
+		return accessClaims{}, "", ErrInvalidToken
+	}
+
+	return claims, claims.Subject, nil
+}
+
+// Valida o desafio emitido por IssueMFAChallenge e retorna o id do usuário
+func (m *TokenManager) ParseMFAChallenge(tokenStr string) (string, error) {
+	_, userId, err := m.verify(tokenStr, purposeMFA)
+	return userId, err
+}
+
+// Valida assinatura e expiração do access token
+func (m *TokenManager) Parse(tokenStr string) (Claims, error) {
+	claims, userId, err := m.verify(tokenStr, purposeAccess)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	role, ok := ParseRole(string(claims.Role))
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return Claims{UserID: userId, SessionID: claims.SessionID, Role: role}, nil
+}
+
+// Token opaco aleatório (ex: refresh token). Só o hash deve ser salvo no banco
+func NewOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Fonte de tempo, trocada por um relógio fixo nos testes
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP (RFC 6238) com HMAC-SHA1, 6 dígitos e passo de 30s, compatível com os apps autenticadores
+type TOTP struct {
+	Issuer string
+	Period time.Duration
+	Digits int
+	// quantos passos antes/depois do atual são aceitos, para tolerar relógios dessincronizados
+	Skew  int64
+	Clock Clock
+}
+
+func NewTOTP(issuer string, clock Clock) *TOTP {
+	return &TOTP{Issuer: issuer, Period: 30 * time.Second, Digits: 6, Skew: 1, Clock: clock}
+}
+
+func (t *TOTP) GenerateSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretEncoding.EncodeToString(buf), nil
+}
+
+// URI para gerar o QR code lido pelos apps autenticadores
+func (t *TOTP) URI(secret string, account string) string {
+	label := url.PathEscape(t.Issuer + ":" + account)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", t.Issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(t.Digits))
+	query.Set("period", fmt.Sprint(int64(t.Period/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func (t *TOTP) step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period/time.Second)
+}
+
+func (t *TOTP) codeAt(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.Digits, value%mod)
+}
+
+// Código válido para o instante informado
+func (t *TOTP) Code(secret string, at time.Time) (string, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return t.codeAt(key, t.step(at)), nil
+}
+
+// Confere o código contra a janela atual do relógio. Retorna o passo em que ele bateu,
+// que deve ser salvo para impedir que o mesmo código seja usado de novo
+func (t *TOTP) Validate(secret string, code string) (int64, bool) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != t.Digits {
+		return 0, false
+	}
+
+	current := t.step(t.Clock.Now())
+	for offset := -t.Skew; offset <= t.Skew; offset++ {
+		step := current + offset
+		if subtle.ConstantTimeCompare([]byte(t.codeAt(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// Códigos de recuperação de uso único no formato xxxx-xxxx
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(secretEncoding.EncodeToString(buf))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+	}
+	return codes, nil
+}
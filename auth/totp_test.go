@@ -0,0 +1,168 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main.go/auth"
+	"main.go/models"
+	"main.go/repository/memory"
+)
+
+// Relógio parado no instante escolhido pelo teste
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+// Base32 do segredo ASCII "12345678901234567890" usado no apêndice B da RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, v := range vectors {
+		clock := &fixedClock{now: time.Unix(v.unix, 0)}
+		totp := auth.NewTOTP("test", clock)
+		// a RFC usa 8 dígitos; os apps autenticadores usam os 6 finais
+		totp.Digits = 8
+
+		code, err := totp.Code(rfcSecret, clock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("Code at %d = %s, want %s", v.unix, code, v.code)
+		}
+
+		if _, ok := totp.Validate(rfcSecret, v.code); !ok {
+			t.Errorf("Validate at %d rejected %s", v.unix, v.code)
+		}
+	}
+}
+
+func TestTOTPValidateAcceptsOnlyTheSkewWindow(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1111111111, 0)}
+	totp := auth.NewTOTP("test", clock)
+	now := clock.Now()
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"one step behind", -totp.Period, true},
+		{"one step ahead", totp.Period, true},
+		{"two steps behind", -2 * totp.Period, false},
+		{"two steps ahead", 2 * totp.Period, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totp.Code(rfcSecret, now.Add(tt.offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := totp.Validate(rfcSecret, code); ok != tt.ok {
+				t.Errorf("Validate = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+
+	if _, ok := totp.Validate(rfcSecret, "12345"); ok {
+		t.Error("Validate accepted a code with the wrong number of digits")
+	}
+}
+
+func TestTOTPCodeCannotBeReplayed(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Unix(1111111111, 0)}
+	totp := auth.NewTOTP("test", clock)
+
+	users := memory.New().Users()
+	id, err := users.Create(ctx, models.User{Name: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.StartTOTPEnrollment(ctx, id, rfcSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	enrollCode, _ := totp.Code(rfcSecret, clock.Now())
+	enrollStep, ok := totp.Validate(rfcSecret, enrollCode)
+	if !ok {
+		t.Fatal("enrollment code rejected")
+	}
+	if err := users.EnableTOTP(ctx, id, rfcSecret, enrollStep, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// o código usado para ativar não serve para login
+	if used, _ := users.UseTOTPStep(ctx, id, enrollStep); used {
+		t.Error("UseTOTPStep accepted the step used at enrollment")
+	}
+
+	clock.now = clock.now.Add(totp.Period)
+	code, _ := totp.Code(rfcSecret, clock.Now())
+	step, ok := totp.Validate(rfcSecret, code)
+	if !ok {
+		t.Fatal("code rejected")
+	}
+	if used, _ := users.UseTOTPStep(ctx, id, step); !used {
+		t.Fatal("UseTOTPStep rejected a fresh step")
+	}
+	if used, _ := users.UseTOTPStep(ctx, id, step); used {
+		t.Error("UseTOTPStep accepted the same step twice")
+	}
+
+	// dentro da tolerância o código anterior ainda bate, mas o passo já ficou para trás
+	previous, ok := totp.Validate(rfcSecret, enrollCode)
+	if !ok {
+		t.Fatal("previous code outside the skew window")
+	}
+	if used, _ := users.UseTOTPStep(ctx, id, previous); used {
+		t.Error("UseTOTPStep accepted an older step")
+	}
+}
+
+func TestMFAChallengeExpires(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	tokens := auth.NewTokenManager([]byte("test-secret"), 15*time.Minute, time.Hour, clock)
+
+	challenge, expiresAt, err := tokens.IssueMFAChallenge("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.now = expiresAt.Add(-time.Second)
+	userId, err := tokens.ParseMFAChallenge(challenge)
+	if err != nil || userId != "user-1" {
+		t.Fatalf("ParseMFAChallenge before expiry = %q, %v", userId, err)
+	}
+
+	// o desafio não vale como access token
+	if _, err := tokens.Parse(challenge); err == nil {
+		t.Error("Parse accepted an mfa_token as access token")
+	}
+
+	clock.now = expiresAt.Add(time.Second)
+	if _, err := tokens.ParseMFAChallenge(challenge); err == nil {
+		t.Error("ParseMFAChallenge accepted an expired challenge")
+	}
+}
@@ -0,0 +1,106 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Descobre o IP de quem fez a requisição. Atrás de um proxy reverso (load balancer,
+// ingress) o endereço da conexão é o do proxy, e o do cliente vem no X-Forwarded-For.
+// Esse header só é lido quando a conexão vem de um proxy confiável, senão qualquer
+// cliente poderia escolher o próprio IP
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// proxies são IPs ou faixas CIDR (ex: 10.0.0.0/8). Sem nenhum, vale sempre o endereço da conexão
+func New(proxies []string) (*Resolver, error) {
+	res := &Resolver{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("proxy %q não é um IP nem uma faixa CIDR", proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		res.trusted = append(res.trusted, prefix.Masked())
+	}
+	return res, nil
+}
+
+func (res *Resolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Percorre o X-Forwarded-For da direita para a esquerda, pulando os proxies confiáveis.
+// O primeiro endereço que não é de um deles é o do cliente
+func (res *Resolver) Resolve(r *http.Request) string {
+	ip := remoteIP(r)
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !res.isTrusted(addr) {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// daqui para a esquerda o header não é confiável
+			return ip
+		}
+
+		ip = addr.Unmap().String()
+		if !res.isTrusted(addr) {
+			return ip
+		}
+	}
+	return ip
+}
+
+type ctxKey struct{}
+
+// Deixa o IP do cliente no contexto para FromRequest. Deve vir antes de quem usa o IP
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ctxKey{}, res.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// IP do cliente resolvido por Middleware. Sem o middleware, o endereço da conexão
+func FromRequest(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,52 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	res, err := New([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"header from untrusted client is ignored", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "192.0.2.10:5000", []string{"198.51.100.1, 10.0.0.5"}, "198.51.100.1"},
+		{"spoofed entry left of the real client", "10.1.2.3:5000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"several headers", "10.1.2.3:5000", []string{"198.51.100.1", "10.0.0.5"}, "198.51.100.1"},
+		{"trusted proxy without header", "10.1.2.3:5000", nil, "10.1.2.3"},
+		{"garbage in header", "10.1.2.3:5000", []string{"not-an-ip"}, "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := res.Resolve(r); got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidProxies(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New accepted an invalid CIDR")
+	}
+	if _, err := New([]string{"proxy.local"}); err == nil {
+		t.Error("New accepted a hostname")
+	}
+}
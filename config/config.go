@@ -0,0 +1,325 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	Database DatabaseConfig `yaml:"database"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Password PasswordConfig `yaml:"password"`
+	Mail     MailConfig     `yaml:"mail"`
+	Uploads  UploadConfig   `yaml:"uploads"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// inclui o corpo, então precisa comportar o envio das imagens
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ao receber SIGINT/SIGTERM, tempo com /readyz falhando antes de parar de aceitar conexões,
+	// para o orquestrador tirar o servidor da rota
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// tempo para as requisições em andamento terminarem depois disso
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// IPs ou faixas CIDR dos proxies reversos cujo X-Forwarded-For é aceito
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type LogConfig struct {
+	// "debug", "info", "warn" ou "error"
+	Level string `yaml:"level"`
+	// "json" ou "text"
+	Format string `yaml:"format"`
+}
+
+type TracingConfig struct {
+	// "none", "stdout" ou "otlp"
+	Exporter string `yaml:"exporter"`
+	// URL do coletor OTLP/HTTP (ex: http://localhost:4318)
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"service_name"`
+	// fração dos traces iniciados aqui que é gravada; traces recebidos seguem a decisão de quem chamou
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type DatabaseConfig struct {
+	// "neo4j" ou "memory"
+	Storage      string        `yaml:"storage"`
+	URI          string        `yaml:"uri"`
+	User         string        `yaml:"user"`
+	Password     string        `yaml:"password"`
+	Name         string        `yaml:"name"`
+	AutoMigrate  bool          `yaml:"auto_migrate"`
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	MaxPoolSize  int           `yaml:"max_pool_size"`
+}
+
+type AuthConfig struct {
+	JWTSecret  string        `yaml:"jwt_secret"`
+	JWTTTL     time.Duration `yaml:"jwt_ttl"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl"`
+	TOTPIssuer string        `yaml:"totp_issuer"`
+}
+
+type PasswordConfig struct {
+	BcryptCost    int  `yaml:"bcrypt_cost"`
+	MinLength     int  `yaml:"min_length"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+}
+
+type MailConfig struct {
+	// "log", "file" ou "smtp"
+	Driver       string `yaml:"driver"`
+	File         string `yaml:"file"`
+	From         string `yaml:"from"`
+	SMTPHost     string `yaml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port"`
+	SMTPUser     string `yaml:"smtp_user"`
+	SMTPPassword string `yaml:"smtp_password"`
+}
+
+type UploadConfig struct {
+	// diretório onde ficam as imagens de usuários e posts
+	Dir string `yaml:"dir"`
+	// tamanho máximo de cada imagem, em bytes
+	MaxFileSize int64 `yaml:"max_file_size"`
+	// quantidade máxima de imagens por post
+	MaxImages int `yaml:"max_images"`
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":3000",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "rede-social",
+			SampleRatio: 1,
+		},
+		Database: DatabaseConfig{
+			Storage:      "neo4j",
+			Name:         "neo4j",
+			AutoMigrate:  true,
+			QueryTimeout: 5 * time.Second,
+			MaxPoolSize:  100,
+		},
+		Auth: AuthConfig{
+			JWTTTL:     15 * time.Minute,
+			RefreshTTL: 30 * 24 * time.Hour,
+			TOTPIssuer: "rede-social",
+		},
+		Password: PasswordConfig{
+			BcryptCost: bcrypt.DefaultCost,
+			MinLength:  8,
+		},
+		Mail: MailConfig{
+			Driver:   "log",
+			File:     "mails.log",
+			SMTPPort: "587",
+		},
+		Uploads: UploadConfig{
+			Dir:         "imgs",
+			MaxFileSize: 50 << 20,
+			MaxImages:   20,
+		},
+	}
+}
+
+// Monta a configuração partindo dos valores padrão. Por ordem de prioridade:
+// variáveis de ambiente, .env (opcional) e o arquivo YAML em path ou CONFIG_FILE (opcional)
+func Load(path string) (Config, error) {
+	// o .env não sobrescreve variáveis já definidas no ambiente
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("não foi possível ler o arquivo .env, erro: %v", err)
+	}
+
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("não foi possível abrir o arquivo de configuração, erro: %v", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	// chave desconhecida no arquivo costuma ser erro de digitação
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("arquivo de configuração %s inválido, erro: %v", path, err)
+	}
+	return nil
+}
+
+// Confere os valores que não dependem do modo de execução. Segredos obrigatórios
+// (ex: JWT_SECRET) são cobrados por quem os usa, já que o "migrate" não precisa deles
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr (ADDR) não pode ser vazio"))
+	}
+	server := c.Server
+	if server.ReadHeaderTimeout < 0 || server.ReadTimeout < 0 || server.WriteTimeout < 0 || server.IdleTimeout < 0 || server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("os timeouts de server não podem ser negativos"))
+	}
+	if server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout (SHUTDOWN_TIMEOUT) deve ser positivo"))
+	}
+	for _, proxy := range server.TrustedProxies {
+		_, prefixErr := netip.ParsePrefix(proxy)
+		_, addrErr := netip.ParseAddr(proxy)
+		if prefixErr != nil && addrErr != nil {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies (TRUSTED_PROXIES): %q não é um IP nem uma faixa CIDR", proxy))
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL desconhecido: %q (use debug, info, warn ou error)", c.Log.Level))
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT desconhecido: %q (use json ou text)", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) é obrigatório com TRACING_EXPORTER=otlp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER desconhecido: %q (use none, stdout ou otlp)", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio (TRACING_SAMPLE_RATIO) deve estar entre 0 e 1"))
+	}
+
+	switch c.Database.Storage {
+	case "neo4j":
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name (DB_NAME) não pode ser vazio"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE desconhecido: %q (use neo4j ou memory)", c.Database.Storage))
+	}
+	if c.Database.MaxPoolSize < 1 {
+		errs = append(errs, errors.New("database.max_pool_size (DB_MAX_POOL_SIZE) deve ser ao menos 1"))
+	}
+	if c.Database.QueryTimeout < 0 {
+		errs = append(errs, errors.New("database.query_timeout (DB_QUERY_TIMEOUT) não pode ser negativo"))
+	}
+
+	if c.Auth.JWTTTL <= 0 || c.Auth.RefreshTTL <= 0 {
+		errs = append(errs, errors.New("auth.jwt_ttl (JWT_TTL) e auth.refresh_ttl (REFRESH_TTL) devem ser positivos"))
+	}
+
+	if c.Password.BcryptCost < bcrypt.MinCost || c.Password.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("password.bcrypt_cost (BCRYPT_COST) deve estar entre %d e %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.Password.MinLength < 1 {
+		errs = append(errs, errors.New("password.min_length (PASSWORD_MIN_LENGTH) deve ser ao menos 1"))
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "file":
+		if c.Mail.File == "" {
+			errs = append(errs, errors.New("mail.file (MAIL_FILE) é obrigatório com MAIL_DRIVER=file"))
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.From == "" {
+			errs = append(errs, errors.New("mail.smtp_host (SMTP_HOST) e mail.from (MAIL_FROM) são obrigatórios com MAIL_DRIVER=smtp"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER desconhecido: %q (use log, file ou smtp)", c.Mail.Driver))
+	}
+
+	if c.Uploads.Dir == "" {
+		errs = append(errs, errors.New("uploads.dir (UPLOAD_DIR) não pode ser vazio"))
+	}
+	if c.Uploads.MaxFileSize <= 0 || c.Uploads.MaxImages <= 0 {
+		errs = append(errs, errors.New("uploads.max_file_size (UPLOAD_MAX_FILE_SIZE) e uploads.max_images (UPLOAD_MAX_IMAGES) devem ser positivos"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Escreve a configuração efetiva em YAML, com os segredos ocultos
+func (c Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (c Config) Redacted() Config {
+	redact := func(secret *string) {
+		if *secret != "" {
+			*secret = "[redacted]"
+		}
+	}
+
+	redact(&c.Database.Password)
+	redact(&c.Auth.JWTSecret)
+	redact(&c.Mail.SMTPPassword)
+	return c
+}
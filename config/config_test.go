@@ -0,0 +1,173 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"main.go/config"
+)
+
+// Tira as variáveis do ambiente durante o teste, inclusive as que o .env definir
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		original, ok := os.LookupEnv(key)
+		os.Unsetenv(key)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(key, original)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	unsetEnv(t, "CONFIG_FILE", "ADDR", "LOG_LEVEL", "LOG_FORMAT", "DB_QUERY_TIMEOUT", "STORAGE")
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), `
+server:
+  addr: ":4000"
+log:
+  level: warn
+  format: text
+`)
+	writeFile(t, filepath.Join(dir, ".env"), "ADDR=:5000\nLOG_LEVEL=error\n")
+	t.Setenv("ADDR", ":6000")
+
+	cfg, err := config.Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ambiente > .env > YAML > padrão
+	if cfg.Server.Addr != ":6000" {
+		t.Errorf("addr = %q, want the environment's :6000", cfg.Server.Addr)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("log level = %q, want the .env's error", cfg.Log.Level)
+	}
+	if cfg.Log.Format != "text" {
+		t.Errorf("log format = %q, want the YAML's text", cfg.Log.Format)
+	}
+	if cfg.Database.QueryTimeout != 5*time.Second {
+		t.Errorf("query timeout = %s, want the default 5s", cfg.Database.QueryTimeout)
+	}
+}
+
+func TestLoadRejectsInvalidSources(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	unsetEnv(t, "CONFIG_FILE", "DB_MAX_POOL_SIZE")
+
+	t.Run("unknown YAML key", func(t *testing.T) {
+		path := filepath.Join(dir, "typo.yaml")
+		writeFile(t, path, "server:\n  adr: \":4000\"\n")
+
+		if _, err := config.Load(path); err == nil {
+			t.Error("Load accepted an unknown key")
+		}
+	})
+
+	t.Run("malformed env var", func(t *testing.T) {
+		t.Setenv("DB_MAX_POOL_SIZE", "many")
+
+		if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "DB_MAX_POOL_SIZE") {
+			t.Errorf("Load error = %v, want one naming DB_MAX_POOL_SIZE", err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	if err := config.Default().Validate(); err != nil {
+		t.Fatalf("default config is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+		// trecho da mensagem de erro
+		want string
+	}{
+		{"empty addr", func(c *config.Config) { c.Server.Addr = "" }, "server.addr"},
+		{"negative timeout", func(c *config.Config) { c.Server.ReadTimeout = -time.Second }, "timeouts de server"},
+		{"no shutdown timeout", func(c *config.Config) { c.Server.ShutdownTimeout = 0 }, "server.shutdown_timeout"},
+		{"bad trusted proxy", func(c *config.Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy"} }, `"proxy"`},
+		{"unknown log level", func(c *config.Config) { c.Log.Level = "verbose" }, "LOG_LEVEL"},
+		{"unknown log format", func(c *config.Config) { c.Log.Format = "xml" }, "LOG_FORMAT"},
+		{"otlp without endpoint", func(c *config.Config) { c.Tracing.Exporter, c.Tracing.Endpoint = "otlp", "" }, "tracing.endpoint"},
+		{"unknown exporter", func(c *config.Config) { c.Tracing.Exporter = "jaeger" }, "TRACING_EXPORTER"},
+		{"sample ratio above 1", func(c *config.Config) { c.Tracing.SampleRatio = 1.5 }, "tracing.sample_ratio"},
+		{"neo4j without database name", func(c *config.Config) { c.Database.Name = "" }, "database.name"},
+		{"unknown storage", func(c *config.Config) { c.Database.Storage = "sqlite" }, "STORAGE"},
+		{"no pool", func(c *config.Config) { c.Database.MaxPoolSize = 0 }, "database.max_pool_size"},
+		{"negative query timeout", func(c *config.Config) { c.Database.QueryTimeout = -time.Second }, "database.query_timeout"},
+		{"no token lifetime", func(c *config.Config) { c.Auth.RefreshTTL = 0 }, "auth.refresh_ttl"},
+		{"bcrypt cost too high", func(c *config.Config) { c.Password.BcryptCost = 40 }, "password.bcrypt_cost"},
+		{"no password length", func(c *config.Config) { c.Password.MinLength = 0 }, "password.min_length"},
+		{"file mail without file", func(c *config.Config) { c.Mail.Driver, c.Mail.File = "file", "" }, "mail.file"},
+		{"smtp without host", func(c *config.Config) { c.Mail.Driver, c.Mail.From = "smtp", "app@example.com" }, "mail.smtp_host"},
+		{"unknown mail driver", func(c *config.Config) { c.Mail.Driver = "sendmail" }, "MAIL_DRIVER"},
+		{"empty upload dir", func(c *config.Config) { c.Uploads.Dir = "" }, "uploads.dir"},
+		{"no images", func(c *config.Config) { c.Uploads.MaxImages = 0 }, "uploads.max_images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.change(&cfg)
+
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Password = "db-secret"
+	cfg.Auth.JWTSecret = "jwt-secret"
+	cfg.Mail.SMTPPassword = "smtp-secret"
+
+	redacted := cfg.Redacted()
+	for _, secret := range []string{redacted.Database.Password, redacted.Auth.JWTSecret, redacted.Mail.SMTPPassword} {
+		if secret != "[redacted]" {
+			t.Errorf("secret left as %q", secret)
+		}
+	}
+	if cfg.Auth.JWTSecret != "jwt-secret" {
+		t.Error("Redacted changed the original config")
+	}
+
+	// segredos vazios continuam vazios, para mostrar que faltam
+	if got := config.Default().Redacted().Auth.JWTSecret; got != "" {
+		t.Errorf("empty secret redacted to %q", got)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"db-secret", "jwt-secret", "smtp-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed config contains %q:\n%s", secret, out.String())
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variáveis de ambiente definidas sobrescrevem o valor atual; as vazias são ignoradas
+func (c *Config) loadEnv() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	envString("ADDR", &c.Server.Addr)
+	check(envDuration("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout))
+	check(envDuration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout))
+	check(envDuration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout))
+	check(envDuration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout))
+	check(envDuration("SHUTDOWN_DELAY", &c.Server.ShutdownDelay))
+	check(envDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout))
+	envList("TRUSTED_PROXIES", &c.Server.TrustedProxies)
+
+	envString("LOG_LEVEL", &c.Log.Level)
+	envString("LOG_FORMAT", &c.Log.Format)
+
+	envString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	envString("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	check(envFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio))
+
+	envString("STORAGE", &c.Database.Storage)
+	envString("URI", &c.Database.URI)
+	envString("USR", &c.Database.User)
+	envString("PSW", &c.Database.Password)
+	envString("DB_NAME", &c.Database.Name)
+	check(envBool("AUTO_MIGRATE", &c.Database.AutoMigrate))
+	check(envDuration("DB_QUERY_TIMEOUT", &c.Database.QueryTimeout))
+	check(envInt("DB_MAX_POOL_SIZE", &c.Database.MaxPoolSize))
+
+	envString("JWT_SECRET", &c.Auth.JWTSecret)
+	check(envDuration("JWT_TTL", &c.Auth.JWTTTL))
+	check(envDuration("REFRESH_TTL", &c.Auth.RefreshTTL))
+	envString("TOTP_ISSUER", &c.Auth.TOTPIssuer)
+
+	check(envInt("BCRYPT_COST", &c.Password.BcryptCost))
+	check(envInt("PASSWORD_MIN_LENGTH", &c.Password.MinLength))
+	check(envBool("PASSWORD_REQUIRE_UPPER", &c.Password.RequireUpper))
+	check(envBool("PASSWORD_REQUIRE_LOWER", &c.Password.RequireLower))
+	check(envBool("PASSWORD_REQUIRE_DIGIT", &c.Password.RequireDigit))
+	check(envBool("PASSWORD_REQUIRE_SYMBOL", &c.Password.RequireSymbol))
+
+	envString("MAIL_DRIVER", &c.Mail.Driver)
+	envString("MAIL_FILE", &c.Mail.File)
+	envString("MAIL_FROM", &c.Mail.From)
+	envString("SMTP_HOST", &c.Mail.SMTPHost)
+	envString("SMTP_PORT", &c.Mail.SMTPPort)
+	envString("SMTP_USER", &c.Mail.SMTPUser)
+	envString("SMTP_PASSWORD", &c.Mail.SMTPPassword)
+
+	envString("UPLOAD_DIR", &c.Uploads.Dir)
+	check(envInt64("UPLOAD_MAX_FILE_SIZE", &c.Uploads.MaxFileSize))
+	check(envInt("UPLOAD_MAX_IMAGES", &c.Uploads.MaxImages))
+
+	return errors.Join(errs...)
+}
+
+func envString(key string, target *string) {
+	if raw := os.Getenv(key); raw != "" {
+		*target = raw
+	}
+}
+
+// Valores separados por vírgula
+func envList(key string, target *[]string) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	*target = values
+}
+
+func envInt(key string, target *int) error {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", key, raw)
+	}
+	*target = value
+	return nil
+}
+
+func envInt64(key string, target *int64) error {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", key, raw)
+	}
+	*target = value
+	return nil
+}
+
+func envFloat(key string, target *float64) error {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q", key, raw)
+	}
+	*target = value
+	return nil
+}
+
+func envBool(key string, target *bool) error {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q (use true ou false)", key, raw)
+	}
+	*target = value
+	return nil
+}
+
+func envDuration(key string, target *time.Duration) error {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("%s inválido: %q (ex: 5s, 15m, 720h)", key, raw)
+	}
+	*target = value
+	return nil
+}
@@ -3,33 +3,86 @@ package db
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
-	"github.com/joho/godotenv"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	neo4jconfig "github.com/neo4j/neo4j-go-driver/v5/neo4j/config"
+	"main.go/config"
+	"main.go/metrics"
+	"main.go/repository"
+	"main.go/repository/memory"
+	"main.go/repository/neo4jrepo"
 )
 
-func InitDB() (neo4j.DriverWithContext, error) {
-	ctx := context.Background()
+// Escolhe o armazenamento por cfg.Storage: "neo4j" (padrão) ou "memory".
+// A função retornada libera a conexão e deve ser chamada ao encerrar
+func InitStorage(cfg config.DatabaseConfig) (repository.Repositories, func(), error) {
+	switch storage := cfg.Storage; storage {
+	case "", "neo4j":
+		driver, err := InitDB(cfg)
+		if err != nil {
+			return repository.Repositories{}, nil, err
+		}
+
+		closeDriver := func() { driver.Close(context.Background()) }
+
+		// AUTO_MIGRATE=false deixa as migrações só para o comando "migrate"
+		if cfg.AutoMigrate {
+			err := neo4jrepo.Migrate(context.Background(), driver, cfg.Name, false, logWriter{})
+			if err != nil {
+				closeDriver()
+				return repository.Repositories{}, nil, fmt.Errorf("não foi possível aplicar as migrações, erro: %v", err)
+			}
+		}
+
+		return neo4jrepo.New(driver, cfg.Name, cfg.QueryTimeout), closeDriver, nil
+	case "memory":
+		slog.Warn("using in-memory storage, data will be lost on exit")
+		return memory.New().Repositories(), func() {}, nil
+	default:
+		return repository.Repositories{}, nil, fmt.Errorf("STORAGE desconhecido: %q (use neo4j ou memory)", storage)
+	}
+}
 
-	err := godotenv.Load(".env")
+// Repassa cada linha escrita para o log, assim as migrações feitas ao iniciar saem no
+// mesmo formato dos outros logs. O comando "migrate" continua escrevendo direto no terminal
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	slog.Info(strings.TrimSpace(string(p)), "component", "migrations")
+	return len(p), nil
+}
+
+// Aplica as migrações pendentes do Neo4j, ou só as lista com dryRun
+func Migrate(cfg config.DatabaseConfig, dryRun bool) error {
+	driver, err := InitDB(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("arquivo de configuração .env não encontrado, erro: %v", err)
+		return err
 	}
+	defer driver.Close(context.Background())
 
-	user := os.Getenv("USR")
-	psw := os.Getenv("PSW")
-	uri := os.Getenv("URI")
+	return neo4jrepo.Migrate(context.Background(), driver, cfg.Name, dryRun, os.Stdout)
+}
+
+func InitDB(cfg config.DatabaseConfig) (neo4j.DriverWithContext, error) {
+	ctx := context.Background()
 
-	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(user, psw, ""))
+	driver, err := neo4j.NewDriverWithContext(cfg.URI, neo4j.BasicAuth(cfg.User, cfg.Password, ""), func(c *neo4jconfig.Config) {
+		c.MaxConnectionPoolSize = cfg.MaxPoolSize
+	})
 	if err != nil {
 		return nil, fmt.Errorf("não foi possível se conectar ao driver do neo4j, erro: %v", err)
 	}
 
 	err = driver.VerifyConnectivity(ctx)
 	if err != nil {
+		// o driver já abriu recursos; quem chama não recebe o driver para fechá-lo
+		driver.Close(ctx)
 		return nil, fmt.Errorf("não foi possível estabelecer uma conexão com o neo4j, erro: %v", err)
 	}
-	fmt.Println("Conexão estabelecida!")
+	metrics.Neo4jMaxConnections.Set(float64(cfg.MaxPoolSize))
+	slog.Info("connected to neo4j", "uri", cfg.URI, "database", cfg.Name)
 	return driver, nil
 }
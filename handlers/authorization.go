@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/auth"
+)
+
+// Checa a política de auth.Can para o usuário autenticado. Toda negação responde 403
+func authorize(w http.ResponseWriter, r *http.Request, action auth.Action, ownerId string) bool {
+	actor, ok := auth.ActorFrom(r.Context())
+	if !ok {
+		apierror.Write(w, r, apierror.Unauthorized("Unauthorized"))
+		return false
+	}
+
+	if !auth.Can(actor, action, ownerId) {
+		forbidden(w, r)
+		return false
+	}
+
+	return true
+}
+
+func forbidden(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.Forbidden("Forbidden"))
+}
+
+func ChangeRoleHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, ok := pathID(w, r, "id")
+		if !ok {
+			return
+		}
+
+		if !authorize(w, r, auth.ActionChangeRole, id) {
+			return
+		}
+
+		var req struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		role, ok := auth.ParseRole(req.Role)
+		if !ok {
+			apierror.Write(w, r, apierror.Validation("Unknown role", nil))
+			return
+		}
+
+		err := app.Users.SetRole(ctx, id, string(role))
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		// o papel vai no access token: encerra as sessões para que um admin rebaixado
+		// não continue com os direitos antigos até o token expirar
+		if err := app.Sessions.RevokeAllExcept(ctx, id, ""); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/auth"
+	"main.go/mail"
+	"main.go/repository"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	// prazo de um envio feito fora da requisição
+	mailTimeout = 30 * time.Second
+)
+
+// Envia o email em segundo plano, com até mailTimeout. A resposta não espera o servidor
+// de email, então nem o tempo dela nem um servidor lento dependem de haver o que enviar
+func sendMailAsync(ctx context.Context, app *app.App, msg mail.Message) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+
+	app.Mails.Add(1)
+	go func() {
+		defer app.Mails.Done()
+		defer cancel()
+
+		if err := app.Mailer.Send(ctx, msg); err != nil {
+			slog.ErrorContext(ctx, "failed to send email", "subject", msg.Subject, "error", err)
+		}
+	}()
+}
+
+// Cria um token de verificação para o endereço e o envia para ele em segundo plano. O endereço fica
+// pendente até ser confirmado; verificações anteriores do usuário deixam de valer
+func createEmailVerification(ctx context.Context, app *app.App, userId string, email string) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	err = app.AccountTokens.CreateEmailVerification(ctx, userId, email, auth.HashToken(token), now, now.Add(emailVerificationTTL))
+	if err != nil {
+		return err
+	}
+
+	sendMailAsync(ctx, app, mail.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Use the code below to confirm this email address. It expires in %s.\n\n%s",
+			emailVerificationTTL, token),
+	})
+	return nil
+}
+
+func ConfirmEmailHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		err := app.AccountTokens.ConfirmEmail(ctx, auth.HashToken(req.Token), time.Now())
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.BadRequest("Invalid or expired verification token"))
+			return
+		}
+		// outra conta passou a usar o endereço enquanto a troca estava pendente
+		if errors.Is(err, repository.ErrConflict) {
+			apierror.Write(w, r, apierror.Conflict("Email already in use"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Envia um novo código para o endereço que falta confirmar: o novo email de uma troca
+// pendente ou, num cadastro ainda não verificado, o próprio email da conta
+func ResendEmailVerificationHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := app.Users.GetByID(ctx, userId)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		email := user.PendingEmail
+		if email == "" && !user.Verified {
+			email = user.Email
+		}
+		if email == "" {
+			apierror.Write(w, r, apierror.Conflict("Email already verified"))
+			return
+		}
+
+		if err := createEmailVerification(ctx, app, user.Id, email); err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to create email verification", err))
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
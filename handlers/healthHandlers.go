@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"main.go/app"
+)
+
+// Pode ser definido no build com -ldflags "-X main.go/handlers.Commit=<hash>".
+// Vazio, usa o commit que o go build grava no binário
+var Commit string
+
+// O processo está de pé. Não consulta dependências, para o orquestrador não reiniciar
+// o servidor só porque o banco caiu
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+// Pronto para receber tráfego: armazenamento acessível, diretório de imagens gravável
+// e servidor fora do encerramento. Responde 503 listando o que falhou
+func ReadyHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := map[string]string{}
+		ready := true
+
+		check := func(name string, err error) {
+			if err != nil {
+				checks[name] = err.Error()
+				ready = false
+				return
+			}
+			checks[name] = "ok"
+		}
+
+		if app.Draining.Load() {
+			checks["shutdown"] = "server is shutting down"
+			ready = false
+		}
+		check("storage", app.Health.Ping(r.Context()))
+		check("images", checkImagesWritable(app.Config.Uploads.Dir))
+
+		status := "ok"
+		code := http.StatusOK
+		if !ready {
+			status = "unavailable"
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]any{"status": status, "checks": checks})
+	}
+}
+
+// Cria e apaga um arquivo temporário no diretório das imagens
+func checkImagesWritable(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func VersionHandler(app *app.App) http.HandlerFunc {
+	info := buildInfo()
+	info["started_at"] = app.StartedAt.UTC().Format(time.RFC3339)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
+
+func buildInfo() map[string]any {
+	info := map[string]any{"commit": Commit, "go_version": runtime.Version()}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				info["commit"] = setting.Value
+			}
+		case "vcs.time":
+			info["commit_time"] = setting.Value
+		case "vcs.modified":
+			info["modified"] = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,72 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// Armazenamento fora do ar
+type downStorage struct{}
+
+func (downStorage) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func TestHealthz(t *testing.T) {
+	s := newTestServer(t)
+
+	expectStatus(t, s.request(http.MethodGet, "/healthz", ""), http.StatusOK)
+
+	// não depende do banco nem do encerramento
+	s.app.Health = downStorage{}
+	s.app.Draining.Store(true)
+	expectStatus(t, s.request(http.MethodGet, "/healthz", ""), http.StatusOK)
+}
+
+func TestReadyz(t *testing.T) {
+	ready := func(t *testing.T, s *testServer, status int) readiness {
+		t.Helper()
+		rec := s.request(http.MethodGet, "/readyz", "")
+		expectStatus(t, rec, status)
+
+		var body readiness
+		decode(t, rec, &body)
+		return body
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		s := newTestServer(t)
+
+		body := ready(t, s, http.StatusOK)
+		if body.Status != "ok" || body.Checks["storage"] != "ok" || body.Checks["images"] != "ok" {
+			t.Errorf("readyz = %+v", body)
+		}
+	})
+
+	t.Run("storage down", func(t *testing.T) {
+		s := newTestServer(t)
+		s.app.Health = downStorage{}
+
+		body := ready(t, s, http.StatusServiceUnavailable)
+		if body.Status != "unavailable" || body.Checks["storage"] != "connection refused" {
+			t.Errorf("readyz = %+v", body)
+		}
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		s := newTestServer(t)
+		s.app.Draining.Store(true)
+
+		body := ready(t, s, http.StatusServiceUnavailable)
+		if body.Checks["shutdown"] == "" || body.Checks["storage"] != "ok" {
+			t.Errorf("readyz = %+v", body)
+		}
+	})
+}
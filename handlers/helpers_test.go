@@ -0,0 +1,284 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+	"main.go/app"
+	"main.go/auth"
+	"main.go/config"
+	"main.go/mail"
+	"main.go/models"
+	"main.go/ratelimit"
+	"main.go/repository"
+	"main.go/repository/memory"
+	"main.go/routes"
+)
+
+const testPassword = "Passw0rd!Long1"
+
+// Guarda os emails enviados, para os testes lerem os tokens
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *fakeMailer) Close() error {
+	return nil
+}
+
+// Espera os envios em segundo plano e retorna o último email enviado
+func (s *testServer) lastMail() mail.Message {
+	s.t.Helper()
+
+	s.app.Mails.Wait()
+	mailer := s.app.Mailer.(*fakeMailer)
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	if len(mailer.sent) == 0 {
+		s.t.Fatal("no email sent")
+	}
+	return mailer.sent[len(mailer.sent)-1]
+}
+
+// Código de uso único enviado no email, sempre na última linha do corpo
+func mailCode(msg mail.Message) string {
+	lines := strings.Split(strings.TrimSpace(msg.Body), "\n")
+	return lines[len(lines)-1]
+}
+
+// Relógio parado no instante escolhido pelo teste
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+// A API inteira sobre o repositório em memória, com as imagens num diretório temporário
+type testServer struct {
+	t      *testing.T
+	app    *app.App
+	router chi.Router
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Uploads.Dir = t.TempDir()
+	cfg.Password.BcryptCost = bcrypt.MinCost
+
+	app := &app.App{
+		Config:       cfg,
+		Repositories: memory.New().Repositories(),
+		Tokens:       auth.NewTokenManager([]byte("test-secret"), cfg.Auth.JWTTTL, cfg.Auth.RefreshTTL, auth.SystemClock{}),
+		TOTP:         auth.NewTOTP(cfg.Auth.TOTPIssuer, auth.SystemClock{}),
+		LoginGuard:   auth.NewLoginGuard(auth.DefaultEmailGuardPolicy, auth.DefaultIPGuardPolicy, auth.SystemClock{}),
+		RateLimiter:  ratelimit.NewMemoryStore(auth.SystemClock{}),
+		Passwords:    auth.InitPasswords(cfg.Password),
+		Mailer:       &fakeMailer{},
+		StartedAt:    time.Now(),
+	}
+
+	router := chi.NewRouter()
+	routes.RegisterRoutes(router, app)
+
+	return &testServer{t: t, app: app, router: router}
+}
+
+func (s *testServer) serve(req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *testServer) request(method string, path string, token string) *httptest.ResponseRecorder {
+	return s.serve(httptest.NewRequest(method, path, nil), token)
+}
+
+func (s *testServer) requestJSON(method string, path string, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	return s.serve(req, token)
+}
+
+// Envia um formulário multipart. files vai do nome do campo para o conteúdo de cada arquivo
+func (s *testServer) requestForm(method string, path string, token string, fields map[string]string, files map[string][][]byte) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	for name, contents := range files {
+		for _, content := range contents {
+			part, err := form.CreateFormFile(name, "image.jpg")
+			if err != nil {
+				s.t.Fatal(err)
+			}
+			part.Write(content)
+		}
+	}
+	form.Close()
+
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return s.serve(req, token)
+}
+
+// Cadastra o usuário e retorna como ele ficou salvo, com o hash da senha
+func (s *testServer) signup(name string, email string) models.User {
+	s.t.Helper()
+
+	rec := s.requestForm(http.MethodPost, "/user/", "", map[string]string{
+		"name":     name,
+		"email":    email,
+		"password": testPassword,
+	}, nil)
+	expectStatus(s.t, rec, http.StatusCreated)
+
+	user, err := s.app.Users.GetByEmail(context.Background(), email)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return user
+}
+
+type loginTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Faz login com a senha dada e retorna os dois tokens
+func (s *testServer) loginWith(email string, password string) loginTokens {
+	s.t.Helper()
+
+	rec := s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": email, "password": password})
+	expectStatus(s.t, rec, http.StatusOK)
+
+	var tokens loginTokens
+	decode(s.t, rec, &tokens)
+	return tokens
+}
+
+// Retorna o access token
+func (s *testServer) login(email string) string {
+	s.t.Helper()
+	return s.loginWith(email, testPassword).AccessToken
+}
+
+func (s *testServer) refresh(refreshToken string) *httptest.ResponseRecorder {
+	return s.requestJSON(http.MethodPost, "/user/token/refresh", "", map[string]string{"refresh_token": refreshToken})
+}
+
+// Troca o papel do usuário e faz login de novo, já que o papel vai no token
+func (s *testServer) loginWithRole(user models.User, role auth.Role) string {
+	s.t.Helper()
+
+	if err := s.app.Users.SetRole(context.Background(), user.Id, string(role)); err != nil {
+		s.t.Fatal(err)
+	}
+	return s.login(user.Email)
+}
+
+// Cria um post e retorna o id dele, o mais recente do autor
+func (s *testServer) createPost(authorId string, token string, description string, images ...[]byte) string {
+	s.t.Helper()
+
+	var files map[string][][]byte
+	if len(images) > 0 {
+		files = map[string][][]byte{"images": images}
+	}
+	rec := s.requestForm(http.MethodPost, "/posts/", token, map[string]string{"description": description}, files)
+	expectStatus(s.t, rec, http.StatusCreated)
+
+	posts, err := s.app.Posts.ListByUser(context.Background(), authorId, repository.Page{Limit: 1})
+	if err != nil || len(posts) == 0 {
+		s.t.Fatalf("post not found after creation: %v", err)
+	}
+	return posts[0].Id
+}
+
+// Valor de uma métrica sem labels, lido de /metrics. Os contadores são globais ao
+// processo, então os testes comparam a diferença antes e depois
+func (s *testServer) metric(name string) float64 {
+	s.t.Helper()
+
+	rec := s.request(http.MethodGet, "/metrics", "")
+	expectStatus(s.t, rec, http.StatusOK)
+	for line := range strings.Lines(rec.Body.String()) {
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(line), name+" "); ok {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				s.t.Fatal(err)
+			}
+			return value
+		}
+	}
+	s.t.Fatalf("metric %s not found", name)
+	return 0
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body)
+	}
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response: %v; body: %s", err, rec.Body)
+	}
+}
+
+// Campos listados em details numa resposta 422
+func invalidFields(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	expectStatus(t, rec, http.StatusUnprocessableEntity)
+
+	var body struct {
+		Details []struct {
+			Field string `json:"field"`
+		} `json:"details"`
+	}
+	decode(t, rec, &body)
+
+	fields := make([]string, 0, len(body.Details))
+	for _, detail := range body.Details {
+		fields = append(fields, detail.Field)
+	}
+	return fields
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/clientip"
+	"main.go/models"
+	"main.go/repository"
+)
+
+// Recusa a tentativa com 429 e Retry-After se o email ou o IP estiverem bloqueados
+func allowLogin(w http.ResponseWriter, r *http.Request, app *app.App, email string) bool {
+	wait := app.LoginGuard.RetryAfter(email, clientip.FromRequest(r))
+	if wait <= 0 {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	apierror.Write(w, r, apierror.TooManyRequests("Too many failed login attempts, try again later"))
+	return false
+}
+
+// Registra a tentativa no histórico do usuário. Falhas aqui não impedem o login
+func recordLoginAttempt(ctx context.Context, app *app.App, r *http.Request, userId string, success bool) {
+	err := app.Users.RecordLoginAttempt(ctx, userId, models.LoginAttempt{
+		IP:        clientip.FromRequest(r),
+		UserAgent: r.UserAgent(),
+		Success:   success,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record login attempt", "error", err)
+	}
+}
+
+func GetLoginAttemptsHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		attempts, err := app.Users.LoginAttempts(ctx, userId, repository.LoginAttemptHistory)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(attempts)
+	}
+}
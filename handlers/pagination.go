@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main.go/apierror"
+	"main.go/repository"
+)
+
+const (
+	defaultPageSize = 20
+	// limites maiores são reduzidos para este valor
+	maxPageSize = 100
+)
+
+// Resposta das listagens. NextCursor é null na última página
+type pageResponse[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Lê ?limit= e ?cursor=. Responde 400 se algum dos dois for inválido
+func parsePage(w http.ResponseWriter, r *http.Request) (repository.Page, bool) {
+	page := repository.Page{Limit: defaultPageSize}
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			apierror.Write(w, r, apierror.BadRequest("Invalid limit"))
+			return page, false
+		}
+		page.Limit = min(limit, maxPageSize)
+	}
+
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		cursor, ok := decodeCursor(value)
+		if !ok {
+			apierror.Write(w, r, apierror.BadRequest("Invalid cursor"))
+			return page, false
+		}
+		page.After = cursor
+	}
+
+	return page, true
+}
+
+// Pede um item a mais que o limite, para saber se existe próxima página
+func nextPageProbe(page repository.Page) repository.Page {
+	page.Limit++
+	return page
+}
+
+// Descarta o item extra pedido por nextPageProbe e, se ele existia, devolve o cursor do último item da página
+func cutPage[T any](items []T, limit int, cursor func(T) repository.Cursor) ([]T, *string) {
+	if len(items) <= limit {
+		return items, nil
+	}
+
+	items = items[:limit]
+	next := encodeCursor(cursor(items[limit-1]))
+	return items, &next
+}
+
+// O cursor é opaco para o cliente: data e id do último item, em base64
+func encodeCursor(cursor repository.Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (repository.Cursor, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return repository.Cursor{}, false
+	}
+
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return repository.Cursor{}, false
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return repository.Cursor{}, false
+	}
+
+	return repository.Cursor{CreatedAt: t, ID: id}, true
+}
+
+func writePage[T any](w http.ResponseWriter, items []T, next *string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pageResponse[T]{Items: items, NextCursor: next})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/auth"
+	"main.go/clientip"
+	"main.go/mail"
+	"main.go/repository"
+)
+
+const passwordResetTTL = time.Hour
+
+func ForgotPasswordHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req forgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		token, err := auth.NewOpaqueToken()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to create reset token", err))
+			return
+		}
+
+		now := time.Now().UTC()
+
+		// tokens anteriores ainda não usados deixam de valer
+		found, err := app.AccountTokens.CreatePasswordReset(ctx, req.Email, auth.HashToken(token), now, now.Add(passwordResetTTL))
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// a resposta é a mesma exista ou não o email, para não revelar contas cadastradas
+		if found {
+			sendMailAsync(ctx, app, mail.Message{
+				To:      req.Email,
+				Subject: "Password reset",
+				Body: fmt.Sprintf("Use the code below to reset your password. It expires in %s.\n\n%s",
+					passwordResetTTL, token),
+			})
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("If the email is registered, a reset code was sent"))
+	}
+}
+
+func ResetPasswordHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req resetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		if err := req.validate(app.Passwords.Policy); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		hashedPassword, err := hashPassword(req.Password, app.Passwords.Cost)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to hash password", err))
+			return
+		}
+
+		// sessões abertas são revogadas pois podem pertencer a quem tomou a conta
+		err = app.AccountTokens.ResetPassword(ctx, auth.HashToken(req.Token), hashedPassword, time.Now())
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.BadRequest("Invalid or expired reset token"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func ChangePasswordHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req changePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		if err := req.validate(app.Passwords.Policy); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		user, err := app.Users.GetByID(ctx, userId)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		// as falhas contam como as do login, senão um access token roubado permitiria
+		// testar senhas sem limite
+		if !allowLogin(w, r, app, user.Email) {
+			return
+		}
+
+		if !checkPasswordHash(req.CurrentPassword, user.Password) {
+			app.LoginGuard.Failure(user.Email, clientip.FromRequest(r))
+			apierror.Write(w, r, apierror.Forbidden("Current password is incorrect"))
+			return
+		}
+		app.LoginGuard.Success(user.Email)
+
+		hashedPassword, err := hashPassword(req.NewPassword, app.Passwords.Cost)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to hash password", err))
+			return
+		}
+
+		if err := app.Users.SetPassword(ctx, userId, hashedPassword); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// mantém apenas a sessão atual (e sua familia de refresh tokens)
+		sessionId, _ := auth.SessionID(r.Context())
+		if err := app.Sessions.RevokeAllExcept(ctx, userId, sessionId); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
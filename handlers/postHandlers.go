@@ -2,99 +2,75 @@ package handlers
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel/attribute"
+	"main.go/apierror"
 	"main.go/app"
+	"main.go/auth"
+	"main.go/config"
+	"main.go/metrics"
 	"main.go/models"
+	"main.go/repository"
+	"main.go/tracing"
 )
 
 func CreatePostHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := r.ParseMultipartForm(0)
-		if err != nil {
-			http.Error(w, "Error parsing multipart form", http.StatusBadRequest)
+		uploads := app.Config.Uploads
+		if !parseUpload(w, r, int64(uploads.MaxImages)*uploads.MaxFileSize) {
 			return
 		}
 
-		userIdStr := r.FormValue("user_id")
-		userId, err := strconv.ParseInt(userIdStr, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid user id", http.StatusBadRequest)
+		userId, ok := actingUser(w, r)
+		if !ok {
 			return
 		}
 
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
-
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User) WHERE id(u) = $user_id
-				CREATE (p:Post {
-					description: $description,
-					created_at: $created_at
-				})
-				CREATE (u)-[:POSTED]->(p)
-				RETURN id(p) AS post_id`,
-			map[string]any{
-				"user_id":     userId,
-				"description": r.FormValue("description"),
-				"created_at":  time.Now().UTC().Format(time.RFC3339),
-			},
-		)
+		ctx := r.Context()
 
-		if err != nil {
-			fmt.Println(err)
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
-			return
+		req := createPostRequest{
+			Description: r.FormValue("description"),
+			Images:      r.MultipartForm.File["images"],
 		}
-
-		record, err := res.Single(ctx)
-		if err != nil {
-			http.Error(w, "Failed to create post", http.StatusInternalServerError)
+		if err := req.validate(uploads); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-		postIdInt, ok := record.Get("post_id")
-		if !ok {
-			http.Error(w, "Failed to retrieve post id", http.StatusInternalServerError)
-			return
-		}
+		// as imagens são gravadas antes e o post é criado já com elas numa única transação
+		postId := repository.NewID()
 
-		postId, ok := postIdInt.(int64)
-		if !ok {
-			http.Error(w, "Invalid post id type", http.StatusInternalServerError)
+		paths, err := addImages(ctx, uploads, userId, postId, req.Images)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-		paths := addImages(w, r, userId, postId)
-
-		_, err = session.Run(
-			ctx,
-			`MATCH (p:Post) WHERE id(p) = $post_id
-			 SET p.images = $images`,
-			map[string]any{
-				"post_id": postId,
-				"images":  paths,
-			},
-		)
-
+		_, err = app.Posts.Create(ctx, userId, models.Post{
+			Id:          postId,
+			Description: req.Description,
+			Images:      paths,
+			CreatedAt:   time.Now().UTC(),
+		})
 		if err != nil {
-			http.Error(w, "Failed to update post images", http.StatusInternalServerError)
+			removePostImages(ctx, uploads, userId, postId)
+			apierror.Write(w, r, err)
 			return
 		}
 
+		metrics.PostsCreated.Inc()
+
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("Post created"))
 
@@ -103,281 +79,216 @@ func CreatePostHandler(app *app.App) http.HandlerFunc {
 
 func DeletePostHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		postId, ok := pathID(w, r, "post-id")
+		if !ok {
 			return
 		}
-		postId, err := strconv.ParseInt(chi.URLParam(r, "post-id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+
+		ownerId, err := app.Posts.Owner(ctx, postId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("Post not found"))
 			return
 		}
-
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User)-[r:POSTED]->(p:Post)
-			 WHERE id(u) = $id AND id(p) = $postId
-			 DETACH DELETE p 
-			 RETURN COUNT(r) as count`,
-			map[string]any{"id": id, "postId": postId},
-		)
-		// usamos detach pois o post esta relacionado a LIKED e POSTED, apenas DELETE só funciona
-		// para nós simples sem relações
-
 		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		record, err := res.Single(ctx)
-		if err != nil {
-			http.Error(w, "Unexpected DB result", http.StatusNotFound)
+		if !authorize(w, r, auth.ActionDeletePost, ownerId) {
 			return
 		}
 
-		count, ok := record.Get("count")
-		if !ok {
-			http.Error(w, "Error getting existence result", http.StatusInternalServerError)
+		err = app.Posts.Delete(ctx, postId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("User or Post not found"))
 			return
 		}
-
-		if count.(int64) == 0 {
-			http.Error(w, "User or Post not found", http.StatusNotFound)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Deleted"))
+		removePostImages(ctx, app.Config.Uploads, ownerId, postId)
+
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func addImages(w http.ResponseWriter, r *http.Request, userId int64, postId int64) []string {
-	files := r.MultipartForm.File["images"]
-	if len(files) > 20 {
-		http.Error(w, "Too many images (max 20 allowed)", http.StatusBadRequest)
-		return nil
+// Espaço no corpo além dos arquivos, para os outros campos e os cabeçalhos de cada parte
+const multipartOverhead = 1 << 20
+
+// Lê o formulário multipart limitando o corpo a maxFiles bytes de arquivos. Sem o limite
+// o cliente poderia encher o disco antes da validação recusar as imagens
+func parseUpload(w http.ResponseWriter, r *http.Request, maxFiles int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxFiles+multipartOverhead)
+
+	if err := r.ParseMultipartForm(0); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Write(w, r, apierror.TooLarge(fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit)))
+			return false
+		}
+		apierror.Write(w, r, apierror.BadRequest("Error parsing multipart form"))
+		return false
 	}
+	return true
+}
+
+func postImagesDir(uploads config.UploadConfig, userId string, postId string) string {
+	return filepath.Join(userImgsDir(uploads, userId), "post"+postId)
+}
 
+// Salva as imagens do post, já validadas. Em caso de erro nenhum arquivo fica para trás
+func addImages(ctx context.Context, uploads config.UploadConfig, userId string, postId string, files []*multipart.FileHeader) ([]string, error) {
 	var imagePaths []string
 	for idx, fileHeader := range files {
-		if fileHeader.Size > (50 << 20) {
-			http.Error(w, "File too large", http.StatusBadRequest)
-			return nil
-		}
-
-		file, err := fileHeader.Open()
+		filename, err := saveImage(ctx, fileHeader, postImagesDir(uploads, userId, postId), fmt.Sprintf("%d.jpg", idx))
 		if err != nil {
-			http.Error(w, "Invalid image", http.StatusBadRequest)
-			return nil
+			removePostImages(ctx, uploads, userId, postId)
+			return nil, err
 		}
-		defer file.Close()
 
-		if err := os.MkdirAll(fmt.Sprintf("imgs/user-%d/post%d/", userId, postId), os.ModePerm); err != nil {
-			http.Error(w, "Failed to save image", http.StatusInternalServerError)
-			return nil
-		}
-		filename := fmt.Sprintf("imgs/user-%d/post%d/%d.jpg", userId, postId, idx)
+		imagePaths = append(imagePaths, filename)
+	}
 
-		outFile, err := os.Create(filename)
-		if err != nil {
-			http.Error(w, "Failed to save image", http.StatusInternalServerError)
-			return nil
-		}
-		defer outFile.Close()
+	return imagePaths, nil
+}
 
-		_, err = io.Copy(outFile, file)
-		if err != nil {
-			http.Error(w, "Failed to save image", http.StatusInternalServerError)
-			return nil
-		}
+// O tamanho já foi conferido na validação da requisição
+func saveImage(ctx context.Context, fileHeader *multipart.FileHeader, dir string, name string) (string, error) {
+	filename := filepath.Join(dir, name)
+	_, span := tracing.Start(ctx, "image.write", attribute.String("file.path", filename))
+	var err error
+	defer func() { tracing.End(span, err) }()
 
-		imagePaths = append(imagePaths, filename)
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", apierror.BadRequest("Invalid image")
 	}
+	defer file.Close()
 
-	return imagePaths
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", apierror.Internal("Failed to save image", err)
+	}
+
+	outFile, err := os.Create(filename)
+	if err != nil {
+		return "", apierror.Internal("Failed to save image", err)
+	}
+	defer outFile.Close()
+
+	written, err := io.Copy(outFile, file)
+	metrics.ImageBytesWritten.Add(float64(written))
+	span.SetAttributes(attribute.Int64("file.size", written))
+	if err != nil {
+		return "", apierror.Internal("Failed to save image", err)
+	}
+
+	return filename, nil
+}
+
+func removePostImages(ctx context.Context, uploads config.UploadConfig, userId string, postId string) {
+	if err := removeImages(ctx, postImagesDir(uploads, userId, postId)); err != nil {
+		slog.ErrorContext(ctx, "failed to remove post images", "post_id", postId, "error", err)
+	}
 }
 
 func GetAllPostsHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		res, err := session.Run(ctx, `
-			MATCH (u:User)-[:POSTED]->(p:Post)
-			RETURN p, id(u) AS userId, u.name AS userName, u.image as profilePicture`, nil)
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		page, ok := parsePage(w, r)
+		if !ok {
 			return
 		}
 
-		posts, err, code := postRecordsToJSON(ctx, res)
+		posts, err := app.Posts.List(ctx, nextPageProbe(page))
 		if err != nil {
-			http.Error(w, err.Error(), code)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(posts)
+		posts, next := cutPage(posts, page.Limit, repository.PostCursor)
+		writePage(w, withPostImageURLs(posts), next)
 	}
 }
 
 func GetPostsFromUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(ctx, `
-			MATCH (u:User)-[:POSTED]->(p:Post)
-			WHERE id(u) = $id
-			RETURN p, id(u) AS userId, u.name AS userName, u.image AS profilePicture
-		`, map[string]any{"id": id})
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		page, ok := parsePage(w, r)
+		if !ok {
 			return
 		}
 
-		posts, err, code := postRecordsToJSON(ctx, res)
+		posts, err := app.Posts.ListByUser(ctx, id, nextPageProbe(page))
 		if err != nil {
-			http.Error(w, err.Error(), code)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		// if err = res.Err(); err != nil {
-		// 	http.Error(w, "Result iteration error", http.StatusInternalServerError)
-		// 	return
-		// }
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(posts)
+		posts, next := cutPage(posts, page.Limit, repository.PostCursor)
+		writePage(w, withPostImageURLs(posts), next)
 	}
 }
 
-func postRecordsToJSON(ctx context.Context, res neo4j.ResultWithContext) ([]models.Post, error, int) {
-	var posts []models.Post
-
-	for res.Next(ctx) {
-		record := res.Record()
+// Troca os caminhos das imagens pelas URLs em que elas são servidas, para que as listagens
+// não carreguem o conteúdo das imagens. Lista vazia vira [], não é um erro
+func withPostImageURLs(posts []models.Post) []models.Post {
+	for i, post := range posts {
+		images := make([]string, len(post.Images))
+		for idx := range post.Images {
+			images[idx] = fmt.Sprintf("/posts/%s/images/%d", post.Id, idx)
+		}
+		posts[i].Images = images
 
-		node, ok := record.Get("p")
-		if !ok {
-			return nil, errors.New("Could not find post"), 404
+		if post.UserImage != "" {
+			posts[i].UserImage = userImageURL(post.UserID)
 		}
+	}
 
-		postNode := node.(neo4j.Node)
-		props := postNode.Props
+	if posts == nil {
+		posts = []models.Post{}
+	}
 
-		userIdRaw, ok := record.Get("userId")
-		if !ok {
-			return nil, errors.New("Could not find user"), 404
-		}
-		userId := int64(userIdRaw.(int64))
+	return posts
+}
 
-		userNameRaw, ok := record.Get("userName")
+func PostImageHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		postId, ok := pathID(w, r, "post-id")
 		if !ok {
-			return nil, errors.New("Could not find user"), 404
-		}
-		userName := userNameRaw.(string)
-
-		var base64Images []string
-		if imagesRaw, ok := props["images"].([]any); ok {
-			for _, img := range imagesRaw {
-				if pathStr, ok := img.(string); ok {
-					imageBytes, err := os.ReadFile(pathStr)
-					if err != nil {
-						log.Printf("Erro ao ler imagem %s: %v", pathStr, err)
-						continue
-					}
-					base64Images = append(base64Images, base64.StdEncoding.EncodeToString(imageBytes))
-				}
-			}
+			return
 		}
 
-		var createdAt time.Time
-		if createdAtStr, ok := props["created_at"].(string); ok {
-			newCreatedAt, err := time.Parse(time.RFC3339, createdAtStr)
-			if err != nil {
-				log.Printf("Erro ao converter created_at: %v", err)
-				createdAt = time.Time{}
-			} else {
-				createdAt = newCreatedAt
-			}
+		index, err := strconv.Atoi(chi.URLParam(r, "index"))
+		if err != nil || index < 0 {
+			apierror.Write(w, r, apierror.BadRequest("Invalid index"))
+			return
 		}
 
-		var post models.Post
-
-		userImagePath, ok := record.Get("profilePicture")
-		if userImagePath != nil {
-			userImage, err := ImageToBase64(userImagePath.(string))
-			if err != nil {
-				return nil, errors.New("Could not convert user image to base64"), 500
-			}
-			post = models.Post{
-				Id:          postNode.GetId(),
-				UserID:      userId,
-				UserName:    userName,
-				Description: props["description"].(string),
-				CreatedAt:   createdAt,
-				Images:      base64Images,
-				UserImage:   userImage,
-			}
-
-		} else {
-			post = models.Post{
-				Id:          postNode.GetId(),
-				UserID:      userId,
-				UserName:    userName,
-				Description: props["description"].(string),
-				CreatedAt:   createdAt,
-				Images:      base64Images,
-			}
-
+		images, err := app.Posts.Images(r.Context(), postId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("Post not found"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
 		}
 
-		// userImagePath, ok := record.Get("profilePicture")
-		// if !ok {
-		// 	return nil, errors.New("Could not get path from user image"), 500
-		// }
-
-		// userImage, err := ImageToBase64(userImagePath.(string))
-		// if err != nil {
-		// 	return nil, errors.New("Could not convert user image to base64"), 500
-		// }
-
-		posts = append(posts, post)
-	}
-
-	if len(posts) == 0 {
-		return nil, errors.New("Not Found"), 404
-	}
-
-	return posts, nil, 200
-}
-
-func getImagesRecord(record *neo4j.Record, prop string) []string {
-	imagesAny, ok := record.Get(prop)
-	var imagesList []string
-	if ok {
-		follows, ok := imagesAny.([]any)
-		if ok {
-			for _, f := range follows {
-				if imageStr, ok := f.(string); ok {
-					imagesList = append(imagesList, imageStr)
-				}
-			}
+		if index >= len(images) {
+			apierror.Write(w, r, apierror.NotFound("Image not found"))
+			return
 		}
-	}
 
-	return imagesList
+		writeImage(w, r, images[index])
+	}
 }
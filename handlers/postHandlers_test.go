@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"main.go/auth"
+)
+
+type postPage struct {
+	Items []struct {
+		Id          string   `json:"id"`
+		UserID      string   `json:"user_id"`
+		Description string   `json:"description"`
+		Images      []string `json:"images"`
+	} `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+func TestCreatePostAndList(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+
+	rec := s.requestForm(http.MethodPost, "/posts/", "", map[string]string{"description": "sem login"}, nil)
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	image := []byte("fake jpeg bytes")
+	postId := s.createPost(alice.Id, token, "primeiro post", image)
+
+	for _, path := range []string{"/posts/", "/posts/" + alice.Id} {
+		var page postPage
+		rec := s.request(http.MethodGet, path, "")
+		expectStatus(t, rec, http.StatusOK)
+		decode(t, rec, &page)
+
+		if len(page.Items) != 1 || page.NextCursor != nil {
+			t.Fatalf("GET %s = %s", path, rec.Body)
+		}
+		post := page.Items[0]
+		if post.Id != postId || post.UserID != alice.Id || post.Description != "primeiro post" {
+			t.Errorf("GET %s: unexpected post %+v", path, post)
+		}
+		if len(post.Images) != 1 {
+			t.Fatalf("GET %s: images = %v", path, post.Images)
+		}
+
+		// a listagem traz só a URL, o conteúdo vem dela
+		rec = s.request(http.MethodGet, post.Images[0], "")
+		expectStatus(t, rec, http.StatusOK)
+		if !bytes.Equal(rec.Body.Bytes(), image) {
+			t.Errorf("GET %s = %q, want %q", post.Images[0], rec.Body, image)
+		}
+	}
+
+	expectStatus(t, s.request(http.MethodGet, "/posts/"+postId+"/images/1", ""), http.StatusNotFound)
+	expectStatus(t, s.request(http.MethodGet, "/posts/"+postId+"/images/x", ""), http.StatusBadRequest)
+	expectStatus(t, s.request(http.MethodGet, "/posts/00000000-0000-0000-0000-000000000000/images/0", ""), http.StatusNotFound)
+}
+
+func TestUploadBodiesAreLimited(t *testing.T) {
+	s := newTestServer(t)
+	s.app.Config.Uploads.MaxFileSize = 1024
+	s.app.Config.Uploads.MaxImages = 2
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+
+	// acima de MaxImages*MaxFileSize mais a folga para os outros campos
+	huge := bytes.Repeat([]byte("x"), 2<<20)
+
+	rec := s.requestForm(http.MethodPost, "/posts/", token, map[string]string{"description": "grande demais"}, map[string][][]byte{"images": {huge}})
+	expectStatus(t, rec, http.StatusRequestEntityTooLarge)
+
+	rec = s.requestForm(http.MethodPost, "/user/", "", map[string]string{
+		"name":     "Bob",
+		"email":    "bob@example.com",
+		"password": testPassword,
+	}, map[string][][]byte{"image": {huge}})
+	expectStatus(t, rec, http.StatusRequestEntityTooLarge)
+
+	// dentro do corpo permitido, os limites por arquivo continuam dando 422
+	small := bytes.Repeat([]byte("x"), 1025)
+	rec = s.requestForm(http.MethodPost, "/posts/", token, map[string]string{"description": "imagem grande"}, map[string][][]byte{"images": {small}})
+	expectStatus(t, rec, http.StatusUnprocessableEntity)
+}
+
+func TestLikeAndDislike(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	postId := s.createPost(alice.Id, s.login(alice.Email), "post")
+	token := s.login(bob.Email)
+
+	likes := s.metric("likes_total")
+	expectStatus(t, s.request(http.MethodPost, "/user/like/"+postId, ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodPost, "/user/like/"+postId, token), http.StatusCreated)
+	expectStatus(t, s.request(http.MethodPost, "/user/like/"+postId, token), http.StatusCreated)
+	if got := s.metric("likes_total") - likes; got != 1 {
+		t.Errorf("likes_total grew by %v, want 1", got)
+	}
+	expectStatus(t, s.request(http.MethodPost, "/user/like/00000000-0000-0000-0000-000000000000", token), http.StatusNotFound)
+	expectStatus(t, s.request(http.MethodPost, "/user/like/not-a-uuid", token), http.StatusBadRequest)
+
+	expectStatus(t, s.request(http.MethodPost, "/user/dislike/"+postId, token), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodPost, "/user/dislike/"+postId, token), http.StatusNotFound)
+}
+
+func TestDeletePost(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	carol := s.signup("Carol", "carol@example.com")
+	aliceToken := s.login(alice.Email)
+	bobToken := s.login(bob.Email)
+
+	first := s.createPost(alice.Id, aliceToken, "primeiro", []byte("image"))
+	second := s.createPost(alice.Id, aliceToken, "segundo")
+
+	dir := filepath.Join(s.app.Config.Uploads.Dir, "user-"+alice.Id, "post"+first)
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("post images not saved: %v", err)
+	}
+
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+first, ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+first, bobToken), http.StatusForbidden)
+
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+first, aliceToken), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+first, aliceToken), http.StatusNotFound)
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("images of a deleted post left on disk: %v", err)
+	}
+
+	// só o dono ou um admin apaga
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+second, s.login(carol.Email)), http.StatusForbidden)
+	adminToken := s.loginWithRole(carol, auth.RoleAdmin)
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+second, adminToken), http.StatusNoContent)
+
+	var page postPage
+	rec := s.request(http.MethodGet, "/posts/", "")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &page)
+	if len(page.Items) != 0 {
+		t.Errorf("deleted posts still listed: %s", rec.Body)
+	}
+}
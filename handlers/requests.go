@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"mime/multipart"
+
+	"main.go/auth"
+	"main.go/config"
+	"main.go/validation"
+)
+
+// Corpos das requisições que alteram dados. validate responde 422 com todos os campos inválidos
+
+type createUserRequest struct {
+	Name     string
+	Email    string
+	Password string
+	// foto de perfil, opcional
+	Image *multipart.FileHeader
+}
+
+func (req createUserRequest) validate(policy auth.PasswordPolicy, uploads config.UploadConfig) error {
+	var v validation.Validator
+	v.Name("name", req.Name)
+	v.Email("email", req.Email)
+	v.Password("password", req.Password, policy)
+	if req.Image != nil {
+		v.Image("image", req.Image, uploads.MaxFileSize)
+	}
+	return v.Err()
+}
+
+type updateUserRequest struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (req updateUserRequest) validate() error {
+	var v validation.Validator
+	v.Name("name", req.Name)
+	v.Email("email", req.Email)
+	return v.Err()
+}
+
+type createPostRequest struct {
+	Description string
+	Images      []*multipart.FileHeader
+}
+
+func (req createPostRequest) validate(uploads config.UploadConfig) error {
+	var v validation.Validator
+	v.MaxLength("description", req.Description, validation.DescriptionMaxLength)
+	v.Images("images", req.Images, uploads.MaxImages, uploads.MaxFileSize)
+	return v.Err()
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (req forgotPasswordRequest) validate() error {
+	var v validation.Validator
+	v.Email("email", req.Email)
+	return v.Err()
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (req resetPasswordRequest) validate(policy auth.PasswordPolicy) error {
+	var v validation.Validator
+	v.Required("token", req.Token)
+	v.Password("password", req.Password, policy)
+	return v.Err()
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (req changePasswordRequest) validate(policy auth.PasswordPolicy) error {
+	var v validation.Validator
+	v.Required("current_password", req.CurrentPassword)
+	v.Password("new_password", req.NewPassword, policy)
+	return v.Err()
+}
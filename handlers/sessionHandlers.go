@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/auth"
+	"main.go/clientip"
+	"main.go/models"
+	"main.go/repository"
+)
+
+type sessionTokens struct {
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// Dados de uma nova sessão. O refresh token em si nunca é salvo, apenas seu hash
+func newSession(app *app.App, r *http.Request, family string, createdAt time.Time) (models.Session, string, error) {
+	sessionId, err := auth.NewID()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return models.Session{}, "", err
+	}
+
+	// primeira sessão da familia (login)
+	if family == "" {
+		family = sessionId
+	}
+
+	now := time.Now().UTC()
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	session := models.Session{
+		Id:         sessionId,
+		Family:     family,
+		TokenHash:  auth.HashToken(refreshToken),
+		UserAgent:  r.UserAgent(),
+		IP:         clientip.FromRequest(r),
+		CreatedAt:  createdAt,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(app.Tokens.RefreshTTL()),
+	}
+
+	return session, refreshToken, nil
+}
+
+// Cria uma nova familia de sessões para o usuário (usado no login)
+func startSession(ctx context.Context, app *app.App, r *http.Request, userId string, role auth.Role) (sessionTokens, error) {
+	session, refreshToken, err := newSession(app, r, "", time.Time{})
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	if err := app.Sessions.Create(ctx, userId, session); err != nil {
+		return sessionTokens{}, err
+	}
+
+	return issueTokens(app, userId, session.Id, role, refreshToken)
+}
+
+func issueTokens(app *app.App, userId string, sessionId string, role auth.Role, refreshToken string) (sessionTokens, error) {
+	accessToken, expiresAt, err := app.Tokens.Issue(userId, sessionId, role)
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	return sessionTokens{
+		SessionID:    sessionId,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+func (t sessionTokens) toJSON() map[string]any {
+	return map[string]any{
+		"access_token":  t.AccessToken,
+		"refresh_token": t.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_at":    t.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func RefreshTokenHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		tokenHash := auth.HashToken(req.RefreshToken)
+
+		current, err := app.Sessions.GetByTokenHash(ctx, tokenHash)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.Unauthorized("Invalid refresh token"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if current.Revoked {
+			apierror.Write(w, r, apierror.Unauthorized("Session revoked"))
+			return
+		}
+
+		// token já foi trocado antes: alguém está reutilizando um refresh token antigo
+		if current.Rotated {
+			if err := app.Sessions.RevokeFamily(ctx, current.Family); err != nil {
+				apierror.Write(w, r, err)
+				return
+			}
+			apierror.Write(w, r, apierror.Unauthorized("Refresh token reuse detected"))
+			return
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			apierror.Write(w, r, apierror.Unauthorized("Refresh token expired"))
+			return
+		}
+
+		// o papel é lido de novo para que mudanças valham no próximo refresh
+		user, err := app.Users.GetByID(ctx, current.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.Unauthorized("Invalid refresh token"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		role, _ := auth.ParseRole(user.Role)
+
+		next, refreshToken, err := newSession(app, r, current.Family, current.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to create session", err))
+			return
+		}
+
+		rotated, err := app.Sessions.Rotate(ctx, tokenHash, next)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// outra requisição rotacionou o mesmo token ao mesmo tempo
+		if !rotated {
+			if err := app.Sessions.RevokeFamily(ctx, current.Family); err != nil {
+				apierror.Write(w, r, err)
+				return
+			}
+			apierror.Write(w, r, apierror.Unauthorized("Refresh token reuse detected"))
+			return
+		}
+
+		tokens, err := issueTokens(app, current.UserID, next.Id, role, refreshToken)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to issue token", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens.toJSON())
+	}
+}
+
+func LogoutHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		sessionId, ok := auth.SessionID(r.Context())
+		if !ok {
+			apierror.Write(w, r, apierror.BadRequest("Token is not bound to a session"))
+			return
+		}
+
+		err := app.Sessions.Revoke(ctx, userId, sessionId)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetSessionsHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+		currentId, _ := auth.SessionID(r.Context())
+
+		sessions, err := app.Sessions.ListActive(ctx, userId, time.Now())
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		for i := range sessions {
+			sessions[i].Current = sessions[i].Id == currentId
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+	}
+}
+
+func DeleteSessionHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := app.Sessions.Revoke(ctx, userId, chi.URLParam(r, "id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("Session not found"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"main.go/apierror"
+	"main.go/app"
+	"main.go/auth"
+	"main.go/clientip"
+	"main.go/models"
+	"main.go/repository"
+)
+
+const recoveryCodeCount = 10
+
+func EnrollTwoFactorHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		secret, err := app.TOTP.GenerateSecret()
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to generate secret", err))
+			return
+		}
+
+		// o segredo fica pendente até o primeiro código ser confirmado
+		user, err := app.Users.StartTOTPEnrollment(ctx, userId, secret)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		if user.TOTPEnabled {
+			apierror.Write(w, r, apierror.Conflict("Two-factor authentication already enabled"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"secret":      secret,
+			"otpauth_uri": app.TOTP.URI(secret, user.Email),
+		})
+	}
+}
+
+func ConfirmTwoFactorHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		user, err := app.Users.GetByID(ctx, userId)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		secret := user.TOTPPendingSecret
+		if secret == "" {
+			apierror.Write(w, r, apierror.Conflict("Two-factor enrollment not started"))
+			return
+		}
+
+		step, ok := app.TOTP.Validate(secret, req.Code)
+		if !ok {
+			apierror.Write(w, r, apierror.BadRequest("Invalid code"))
+			return
+		}
+
+		codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to generate recovery codes", err))
+			return
+		}
+
+		hashes := make([]string, len(codes))
+		for i, code := range codes {
+			hashes[i] = auth.HashToken(code)
+		}
+
+		// ErrNotFound aqui quer dizer que outro enroll trocou o segredo pendente no meio tempo
+		err = app.Users.EnableTOTP(ctx, userId, secret, step, hashes)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.Conflict("Two-factor enrollment not started"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// os códigos em claro só são mostrados agora
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"recovery_codes": codes})
+	}
+}
+
+// Segunda etapa do login: troca o mfa_token de LoginHandler e um código TOTP
+// (ou de recuperação) por uma sessão
+func LoginTwoFactorHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var req struct {
+			MFAToken     string `json:"mfa_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		userId, err := app.Tokens.ParseMFAChallenge(req.MFAToken)
+		if err != nil {
+			apierror.Write(w, r, apierror.Unauthorized("Invalid or expired mfa token"))
+			return
+		}
+
+		user, err := app.Users.GetByID(ctx, userId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.Unauthorized("User not found"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// os códigos também contam para o bloqueio, senão dariam para ser adivinhados
+		if !allowLogin(w, r, app, user.Email) {
+			return
+		}
+
+		valid, err := useSecondFactor(ctx, app, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		if !valid {
+			app.LoginGuard.Failure(user.Email, clientip.FromRequest(r))
+			recordLoginAttempt(ctx, app, r, user.Id, false)
+			apierror.Write(w, r, apierror.Unauthorized("Invalid code"))
+			return
+		}
+
+		completeLogin(ctx, w, r, app, user)
+	}
+}
+
+// Desliga o 2FA. Exige um código TOTP ou de recuperação válido, além do access token
+func DisableTwoFactorHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		user, err := app.Users.GetByID(ctx, userId)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		if !user.TOTPEnabled {
+			apierror.Write(w, r, apierror.Conflict("Two-factor authentication not enabled"))
+			return
+		}
+
+		if !allowLogin(w, r, app, user.Email) {
+			return
+		}
+
+		valid, err := useSecondFactor(ctx, app, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		if !valid {
+			app.LoginGuard.Failure(user.Email, clientip.FromRequest(r))
+			apierror.Write(w, r, apierror.Unauthorized("Invalid code"))
+			return
+		}
+
+		err = app.Users.DisableTOTP(ctx, userId)
+		if !checkUserFound(w, r, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Consome o código TOTP ou, se ele vier vazio, o código de recuperação
+func useSecondFactor(ctx context.Context, app *app.App, user models.User, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		return useTOTPCode(ctx, app, user, code)
+	}
+	return app.Users.UseRecoveryCode(ctx, user.Id, auth.HashToken(strings.ToLower(strings.TrimSpace(recoveryCode))))
+}
+
+// Valida o código e grava o passo usado, para que o mesmo código não sirva duas vezes
+func useTOTPCode(ctx context.Context, app *app.App, user models.User, code string) (bool, error) {
+	if !user.TOTPEnabled || user.TOTPSecret == "" {
+		return false, nil
+	}
+
+	step, ok := app.TOTP.Validate(user.TOTPSecret, code)
+	if !ok {
+		return false, nil
+	}
+
+	return app.Users.UseTOTPStep(ctx, user.Id, step)
+}
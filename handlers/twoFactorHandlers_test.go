@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"main.go/auth"
+)
+
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	AccessToken string `json:"access_token"`
+}
+
+// Liga o 2FA do usuário com o TOTP do servidor preso em clock e retorna o segredo e os
+// códigos de recuperação
+func enableTwoFactor(t *testing.T, s *testServer, clock *fixedClock, token string) (string, []string) {
+	t.Helper()
+
+	rec := s.request(http.MethodPost, "/user/2fa/enroll", token)
+	expectStatus(t, rec, http.StatusOK)
+	var enrollment struct {
+		Secret string `json:"secret"`
+	}
+	decode(t, rec, &enrollment)
+
+	code, err := s.app.TOTP.Code(enrollment.Secret, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = s.requestJSON(http.MethodPost, "/user/2fa/confirm", token, map[string]string{"code": code})
+	expectStatus(t, rec, http.StatusOK)
+	var confirmation struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	decode(t, rec, &confirmation)
+
+	return enrollment.Secret, confirmation.RecoveryCodes
+}
+
+// Primeira etapa do login, com a senha
+func passwordLogin(t *testing.T, s *testServer, email string) mfaChallenge {
+	t.Helper()
+
+	rec := s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": email, "password": testPassword})
+	expectStatus(t, rec, http.StatusOK)
+	var challenge mfaChallenge
+	decode(t, rec, &challenge)
+	return challenge
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	s.app.TOTP = auth.NewTOTP("test", clock)
+
+	alice := s.signup("Alice", "alice@example.com")
+	secret, recoveryCodes := enableTwoFactor(t, s, clock, s.login(alice.Email))
+	if len(recoveryCodes) == 0 {
+		t.Fatal("no recovery codes")
+	}
+
+	secondStep := func(body map[string]string) *httptest.ResponseRecorder {
+		return s.requestJSON(http.MethodPost, "/user/login/2fa", "", body)
+	}
+
+	// com 2FA a senha sozinha não cria sessão
+	challenge := passwordLogin(t, s, alice.Email)
+	if !challenge.MFARequired || challenge.MFAToken == "" || challenge.AccessToken != "" {
+		t.Fatalf("password login = %+v, want only an mfa token", challenge)
+	}
+
+	clock.now = clock.now.Add(30 * time.Second)
+	code, err := s.app.TOTP.Code(secret, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := secondStep(map[string]string{"mfa_token": challenge.MFAToken, "code": code})
+	expectStatus(t, rec, http.StatusOK)
+	var tokens loginTokens
+	decode(t, rec, &tokens)
+	if tokens.AccessToken == "" {
+		t.Fatalf("second step without access token: %s", rec.Body)
+	}
+
+	// o mesmo código não vale de novo no mesmo passo
+	challenge = passwordLogin(t, s, alice.Email)
+	expectStatus(t, secondStep(map[string]string{"mfa_token": challenge.MFAToken, "code": code}), http.StatusUnauthorized)
+
+	// código de recuperação vale uma vez só
+	recovery := map[string]string{"mfa_token": challenge.MFAToken, "recovery_code": recoveryCodes[0]}
+	expectStatus(t, secondStep(recovery), http.StatusOK)
+	expectStatus(t, secondStep(recovery), http.StatusUnauthorized)
+}
+
+func TestDisableTwoFactorRequiresAValidCode(t *testing.T) {
+	s := newTestServer(t)
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	s.app.TOTP = auth.NewTOTP("test", clock)
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+	secret, _ := enableTwoFactor(t, s, clock, token)
+
+	// código de um passo fora da janela aceita
+	wrong, err := s.app.TOTP.Code(secret, clock.now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/2fa/disable", token, map[string]string{}), http.StatusBadRequest)
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/2fa/disable", token, map[string]string{"code": wrong}), http.StatusUnauthorized)
+	if challenge := passwordLogin(t, s, alice.Email); !challenge.MFARequired {
+		t.Fatal("2FA disabled by an invalid code")
+	}
+
+	clock.now = clock.now.Add(30 * time.Second)
+	code, err := s.app.TOTP.Code(secret, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/2fa/disable", token, map[string]string{"code": code}), http.StatusNoContent)
+
+	if challenge := passwordLogin(t, s, alice.Email); challenge.MFARequired || challenge.AccessToken == "" {
+		t.Errorf("login after disabling 2FA = %+v, want a session", challenge)
+	}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/2fa/disable", token, map[string]string{"code": code}), http.StatusConflict)
+}
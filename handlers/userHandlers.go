@@ -2,107 +2,120 @@ package handlers
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
+	"io/fs"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"os"
-	"strconv"
+	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/bcrypt"
+	"main.go/apierror"
 	"main.go/app"
+	"main.go/auth"
+	"main.go/clientip"
+	"main.go/config"
+	"main.go/logging"
+	"main.go/metrics"
 	"main.go/models"
+	"main.go/repository"
+	"main.go/tracing"
 )
 
 func CreateUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		name := r.FormValue("name")
-		email := r.FormValue("email")
-		password := r.FormValue("password")
+		if !parseUpload(w, r, app.Config.Uploads.MaxFileSize) {
+			return
+		}
+
+		req := createUserRequest{
+			Name:     r.FormValue("name"),
+			Email:    r.FormValue("email"),
+			Password: r.FormValue("password"),
+		}
+		// user tem img
+		if _, fileHeader, err := r.FormFile("image"); err == nil {
+			req.Image = fileHeader
+		}
 
-		if name == "" || email == "" || password == "" {
-			http.Error(w, "Missing required fields", http.StatusBadRequest)
+		if err := req.validate(app.Passwords.Policy, app.Config.Uploads); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
 		// Verifica se o email ja existe
-		exists, err := emailExists(ctx, session, email)
+		exists, err := app.Users.EmailExists(ctx, req.Email)
 		if err != nil {
-			http.Error(w, "Failed to check email", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal("Failed to check email", err))
 			return
 		}
 		if exists {
-			http.Error(w, "Email already in use", http.StatusConflict)
+			apierror.Write(w, r, apierror.Conflict("Email already in use"))
 			return
 		}
 
-		hashedPassword, err := hashPassword(password)
+		hashedPassword, err := hashPassword(req.Password, app.Passwords.Cost)
 		if err != nil {
-			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal("Failed to hash password", err))
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`CREATE (u:User {name: $name, email: $email, password: $password})
-			 RETURN id(u) AS id`,
-			map[string]any{"name": name, "email": email, "password": hashedPassword},
-		)
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		// o id é gerado antes para a imagem já ser salva no diretório do usuário
+		userId := repository.NewID()
+		if err := createUserImgsDir(ctx, app.Config.Uploads, userId); err != nil {
+			apierror.Write(w, r, apierror.Internal("Failed to save image", err))
 			return
 		}
 
-		record, err := res.Single(ctx)
-		if err != nil {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
+		var image string
+		if req.Image != nil {
+			filename, err := createProfilePicture(ctx, app.Config.Uploads, userId, req.Image)
+			if err != nil {
+				removeUserImgsDir(ctx, app.Config.Uploads, userId)
+				apierror.Write(w, r, err)
+				return
+			}
+			image = filename
 		}
 
-		resId, ok := record.Get("id")
-		if !ok {
-			http.Error(w, "Error getting user ID", http.StatusInternalServerError)
+		_, err = app.Users.Create(ctx, models.User{
+			Id:        userId,
+			Name:      req.Name,
+			Email:     req.Email,
+			Password:  hashedPassword,
+			Role:      string(auth.RoleUser),
+			Image:     image,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			// sem o usuário os arquivos ficariam órfãos
+			removeUserImgsDir(ctx, app.Config.Uploads, userId)
+		}
+		// cadastro concorrente com o mesmo email, barrado pela constraint
+		if errors.Is(err, repository.ErrConflict) {
+			apierror.Write(w, r, apierror.Conflict("Email already in use"))
 			return
 		}
-
-		userId, ok := resId.(int64)
-		if !ok {
-			http.Error(w, "Invalid user ID type", http.StatusInternalServerError)
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
-		createUserImgsDir(userId)
-
-		_, fileHeader, err := r.FormFile("image")
-		// user tem img
-		if err == nil {
-
-			filename, err, code := createProfilePicture(userId, fileHeader)
-			if err != nil {
-				http.Error(w, err.Error(), code)
-			}
-
-			_, err = session.Run(
-				ctx,
-				`MATCH (u:User) WHERE id(u) = $id SET u.image = $imagePath`,
-				map[string]any{"id": userId, "imagePath": filename},
-			)
-			if err != nil {
-				http.Error(w, "Failed to update image path", http.StatusInternalServerError)
-				return
-			}
+		// a conta já existe; o usuário pode pedir o código de novo em POST /user/email/resend
+		if err := createEmailVerification(ctx, app, userId, req.Email); err != nil {
+			slog.ErrorContext(ctx, "failed to create email verification", "error", err)
 		}
 
+		metrics.Signups.Inc()
+
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("User created"))
 	}
@@ -110,9 +123,7 @@ func CreateUserHandler(app *app.App) http.HandlerFunc {
 
 func LoginHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
 		type LoginRequest struct {
 			Email    string `json:"email"`
@@ -121,260 +132,275 @@ func LoginHandler(app *app.App) http.HandlerFunc {
 
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
 			return
 		}
 
-		res, err := session.Run(ctx,
-			`MATCH (u:User)
-			 WHERE u.email = $email
-			 RETURN u.password AS hash, id(u) as id, properties(u) as props`,
-			map[string]any{"email": req.Email})
-
-		if err != nil || !res.Next(ctx) {
-			http.Error(w, "User not found", http.StatusUnauthorized)
+		if !allowLogin(w, r, app, req.Email) {
 			return
 		}
 
-		record := res.Record()
-		storedHash := record.Values[0].(string)
+		user, err := app.Users.GetByEmail(ctx, req.Email)
+		if errors.Is(err, repository.ErrNotFound) {
+			checkPasswordHash(req.Password, app.Passwords.DummyHash)
+			app.LoginGuard.Failure(req.Email, clientip.FromRequest(r))
+			apierror.Write(w, r, apierror.Unauthorized("Invalid email or password"))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
 
-		if !checkPasswordHash(req.Password, storedHash) {
-			http.Error(w, "Invalid password", http.StatusUnauthorized)
+		if !checkPasswordHash(req.Password, user.Password) {
+			app.LoginGuard.Failure(req.Email, clientip.FromRequest(r))
+			recordLoginAttempt(ctx, app, r, user.Id, false)
+			apierror.Write(w, r, apierror.Unauthorized("Invalid email or password"))
 			return
 		}
 
-		userId := record.Values[1].(int64)
-		props := record.Values[2].(map[string]any)
+		// hash antigo com custo menor que o configurado: aproveita a senha em claro para refazer
+		if app.Passwords.NeedsRehash(user.Password) {
+			if err := rehashPassword(ctx, app, user.Id, req.Password); err != nil {
+				slog.ErrorContext(ctx, "failed to rehash password", "error", err)
+			}
+		}
 
-		user := map[string]any{
-			"id":    userId,
-			"name":  props["name"],
-			"email": props["email"],
+		// com 2FA ativo a sessão só é criada em LoginTwoFactorHandler
+		if user.TOTPEnabled {
+			challenge, expiresAt, err := app.Tokens.IssueMFAChallenge(user.Id)
+			if err != nil {
+				apierror.Write(w, r, apierror.Internal("Failed to issue token", err))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"mfa_required": true,
+				"mfa_token":    challenge,
+				"expires_at":   expiresAt.UTC().Format(time.RFC3339),
+			})
+			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(user)
+		completeLogin(ctx, w, r, app, user)
+	}
+}
+
+// Cria a sessão e responde com os tokens e os dados do usuário
+func completeLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, app *app.App, user models.User) {
+	logging.SetUserID(ctx, user.Id)
+	app.LoginGuard.Success(user.Email)
+	recordLoginAttempt(ctx, app, r, user.Id, true)
+
+	role, _ := auth.ParseRole(user.Role)
+
+	tokens, err := startSession(ctx, app, r, user.Id, role)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal("Failed to create session", err))
+		return
 	}
+
+	response := tokens.toJSON()
+	response["id"] = user.Id
+	response["name"] = user.Name
+	response["email"] = user.Email
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
-func createUserImgsDir(id int64) {
-	if err := os.MkdirAll(fmt.Sprintf("imgs/user-%d", id), os.ModePerm); err != nil {
-		log.Fatal(err)
+func userImgsDir(uploads config.UploadConfig, id string) string {
+	return filepath.Join(uploads.Dir, "user-"+id)
+}
+
+func createUserImgsDir(ctx context.Context, uploads config.UploadConfig, id string) (err error) {
+	dir := userImgsDir(uploads, id)
+	_, span := tracing.Start(ctx, "image.mkdir", attribute.String("file.path", dir))
+	defer func() { tracing.End(span, err) }()
+
+	return os.MkdirAll(dir, os.ModePerm)
+}
+
+func removeUserImgsDir(ctx context.Context, uploads config.UploadConfig, id string) {
+	if err := removeImages(ctx, userImgsDir(uploads, id)); err != nil {
+		slog.ErrorContext(ctx, "failed to remove user images", "user_id", id, "error", err)
 	}
 }
 
 func GetAllUsersHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
-
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User)
-			RETURN u`,
-			nil,
-		)
+		ctx := r.Context()
 
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		page, ok := parsePage(w, r)
+		if !ok {
 			return
 		}
 
-		usersJson, err, code := usersToJson(ctx, res, "u")
+		users, err := app.Users.List(ctx, nextPageProbe(page))
 		if err != nil {
-			http.Error(w, err.Error(), code)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		w.Header().Set("Conten-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(usersJson)
+		users, next := cutPage(users, page.Limit, repository.UserCursor)
+		writePage(w, toPublicUsers(users), next)
 	}
-
 }
 
 func GetUserByIdHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User) WHERE id(u) = $id
-			 RETURN 
-				id(u) AS id, 
-				properties(u) AS props`,
-			map[string]any{"id": id},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		user, err := app.Users.GetByID(ctx, id)
+		if !checkUserFound(w, r, err) {
 			return
 		}
 
-		user := recordToJSON(ctx, w, res)
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(user)
+		writeUser(w, r, user)
 	}
 }
 
 func GetProfileHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		requesterId, err := strconv.ParseInt(chi.URLParam(r, "requesterId"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
-			return
-		}
-
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User) WHERE id(u) = $profileId
-			 OPTIONAL MATCH (requester:User)-[:FOLLOWS]->(u)
-			 WHERE id(requester) = $requesterId
-			 OPTIONAL MATCH (u)-[:POSTED]->(p:Post)
-			 OPTIONAL MATCH (follower:User)-[:FOLLOWS]->(u)
-			 OPTIONAL MATCH (u)-[:FOLLOWS]->(followed:User)
-			 RETURN 
-				id(u) AS id, 
-				properties(u) AS props,
-				CASE WHEN requester IS NULL THEN false ELSE true END AS isFollower, 
-			    COUNT(DISTINCT p) as postCount, 
-			    COUNT(DISTINCT follower) as totalFollowers,
-			    COUNT(DISTINCT followed) as totalFollowed`,
-			map[string]any{"profileId": id, "requesterId": requesterId},
-		)
+		requesterId, ok := actingUser(w, r)
+		if !ok {
+			return
+		}
 
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		user, stats, err := app.Users.Profile(ctx, id, requesterId)
+		if !checkUserFound(w, r, err) {
 			return
 		}
 
-		user := recordToJSON(ctx, w, res)
+		user = withImageURL(user)
+
+		profile := models.Profile{
+			PublicUser: user.Public(),
+			Follows:    stats.Follows,
+			PostCount:  stats.PostCount,
+			Followers:  stats.Followers,
+			Following:  stats.Following,
+		}
+		if user.Id == requesterId {
+			profile.Email = user.Email
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write(user)
+		json.NewEncoder(w).Encode(profile)
 	}
 }
 
 func GetUserByEmailHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
-
-		email := chi.URLParam(r, "email")
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User) WHERE u.email = $email 
-			 RETURN 
-				id(u) AS id, 
-				properties(u) AS props`,
-			map[string]any{"email": email},
-		)
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		ctx := r.Context()
+
+		if !authorize(w, r, auth.ActionLookupEmail, "") {
 			return
 		}
 
-		user := recordToJSON(ctx, w, res)
+		user, err := app.Users.GetByEmail(ctx, chi.URLParam(r, "email"))
+		if !checkUserFound(w, r, err) {
+			return
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(user)
+		writeUser(w, r, user)
 	}
 }
 
 func UpdateUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		var user struct {
-			Id    int64  `json:"id"`
-			Name  string `json:"name"`
-			Email string `json:"email"`
-		}
+		var user updateUserRequest
 
 		decoder := json.NewDecoder(r.Body)
 		decoder.DisallowUnknownFields()
 
-		err := decoder.Decode(&user)
-		if err != nil || user.Name == "" || user.Email == "" {
-			http.Error(w, "Invalid JSON or missing required fields", http.StatusBadRequest)
-			return
-		}
-
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User) 
-			 WHERE id(u) = $id 
-			 SET u.name = $name, u.email = $email
-			 RETURN 
-				id(u) AS id, 
-				properties(u) AS props`,
-			map[string]any{
-				"id":    user.Id,
-				"name":  user.Name,
-				"email": user.Email,
-			},
-		)
+		if err := decoder.Decode(&user); err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid request body"))
+			return
+		}
+
+		if err := user.validate(); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// sem id no corpo o usuário atualiza o próprio perfil
+		if user.Id == "" {
+			userId, ok := actingUser(w, r)
+			if !ok {
+				return
+			}
+			user.Id = userId
+		}
+
+		if !authorize(w, r, auth.ActionUpdateUser, user.Id) {
+			return
+		}
+
+		taken, err := app.Users.EmailTakenByOther(ctx, user.Email, user.Id)
 		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Internal("Failed to check email", err))
+			return
+		}
+		if taken {
+			apierror.Write(w, r, apierror.Conflict("Email already in use"))
 			return
 		}
 
-		newUser := recordToJSON(ctx, w, res)
+		newUser, err := app.Users.UpdateName(ctx, user.Id, user.Name)
+		if !checkUserFound(w, r, err) {
+			return
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(newUser)
+		// o email novo só passa a valer depois de confirmado
+		if newUser.Email != user.Email {
+			if err := createEmailVerification(ctx, app, newUser.Id, user.Email); err != nil {
+				apierror.Write(w, r, apierror.Internal("Failed to create email verification", err))
+				return
+			}
+			newUser.PendingEmail = user.Email
+		}
+
+		writeUser(w, r, newUser)
 	}
 }
 
 func DeleteUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
-		_, err = session.Run(
-			ctx,
-			"MATCH (u: User) WHERE id(u) = $id DELETE u",
-			map[string]any{"id": id},
-		)
 
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		if !authorize(w, r, auth.ActionDeleteUser, id) {
+			return
+		}
+
+		// remove junto os posts, sessões, tentativas de login e tokens do usuário, que não fazem sentido sem ele
+		err := app.Users.Delete(ctx, id)
+		if !checkUserFound(w, r, err) {
 			return
 		}
+		// a foto de perfil e as imagens dos posts ficam todas no diretório do usuário
+		removeUserImgsDir(ctx, app.Config.Uploads, id)
 
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -382,52 +408,33 @@ func DeleteUserHandler(app *app.App) http.HandlerFunc {
 
 func FollowUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		userId, ok := actingUser(w, r)
+		if !ok {
 			return
 		}
 
-		otherId, err := strconv.ParseInt(chi.URLParam(r, "second-id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		otherId, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (a:User), (b:User) 
-			 WHERE id(a) = $userId AND id(b) = $otherId 
-			 MERGE (a)-[r:FOLLOWS]->(b)
-			 RETURN COUNT(r) as count`,
-			map[string]any{"userId": userId, "otherId": otherId},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		created, err := app.Users.Follow(ctx, userId, otherId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
 			return
 		}
-
-		record, err := res.Single(ctx)
 		if err != nil {
-			http.Error(w, "Unexpected DB result", http.StatusNotFound)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		count, ok := record.Get("count")
-		if !ok {
-			http.Error(w, "Error getting existence result", http.StatusInternalServerError)
-			return
+		// seguir de novo responde igual, mas não conta como um novo follow
+		if created {
+			metrics.Follows.Inc()
 		}
 
-		if count.(int64) == 0 {
-			http.Error(w, "Users not found", http.StatusNotFound)
-			return
-		}
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("Followed"))
 	}
@@ -435,185 +442,110 @@ func FollowUserHandler(app *app.App) http.HandlerFunc {
 
 func UnfollowUserHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		userId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		userId, ok := actingUser(w, r)
+		if !ok {
 			return
 		}
 
-		otherId, err := strconv.ParseInt(chi.URLParam(r, "second-id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		otherId, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (a:User)-[r:FOLLOWS]->(b:User) 
-			 WHERE id(a) = $userId AND id(b) = $otherId 
-			 DELETE r
-			 RETURN COUNT(r) as count`,
-			map[string]any{"userId": userId, "otherId": otherId},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		err := app.Users.Unfollow(ctx, userId, otherId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("Not following this user"))
 			return
 		}
-
-		record, err := res.Single(ctx)
 		if err != nil {
-			http.Error(w, "Unexpected DB result", http.StatusNotFound)
-			return
-		}
-
-		count, ok := record.Get("count")
-		if !ok {
-			http.Error(w, "Error getting deletion result", http.StatusInternalServerError)
-			return
-		}
-
-		if count.(int64) == 0 {
-			http.Error(w, "No following relantionship", http.StatusNotFound)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Unfollowed"))
-
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
 func GetFollowersHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (target: User) 
-			 WHERE id(target) = $id
-			 MATCH (follower:User)-[:FOLLOWS]->(target)
-			 RETURN follower`,
-			map[string]any{"id": id},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		page, ok := parsePage(w, r)
+		if !ok {
 			return
 		}
 
-		usersJson, err, code := usersToJson(ctx, res, "follower")
+		users, err := app.Users.Followers(ctx, id, nextPageProbe(page))
 		if err != nil {
-			http.Error(w, err.Error(), code)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		w.Header().Set("Conten-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(usersJson)
-
+		users, next := cutPage(users, page.Limit, repository.UserCursor)
+		writePage(w, toPublicUsers(users), next)
 	}
 }
 
 func GetFollowingHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := pathID(w, r, "id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (u: User) 
-			 WHERE id(u) = $id
-			 MATCH (u)-[:FOLLOWS]->(followed:User)
-			 RETURN followed`,
-			map[string]any{"id": id},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		page, ok := parsePage(w, r)
+		if !ok {
 			return
 		}
 
-		usersJson, err, code := usersToJson(ctx, res, "followed")
+		users, err := app.Users.Following(ctx, id, nextPageProbe(page))
 		if err != nil {
-			http.Error(w, err.Error(), code)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		w.Header().Set("Conten-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(usersJson)
-
+		users, next := cutPage(users, page.Limit, repository.UserCursor)
+		writePage(w, toPublicUsers(users), next)
 	}
 }
 
 func LikePostHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := actingUser(w, r)
+		if !ok {
 			return
 		}
 
-		postId, err := strconv.ParseInt(chi.URLParam(r, "post-id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		postId, ok := pathID(w, r, "post-id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User), (p:Post)
-			 WHERE id(u) = $id AND id(p) = $postId
-			 MERGE (u)-[r:LIKED]->(p)
-			 RETURN COUNT(r) as count`,
-			map[string]any{"id": id, "postId": postId},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		created, err := app.Posts.Like(ctx, id, postId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("User or Post not found"))
 			return
 		}
-
-		record, err := res.Single(ctx)
 		if err != nil {
-			http.Error(w, "Unexpected DB result", http.StatusNotFound)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		count, ok := record.Get("count")
-		if !ok {
-			http.Error(w, "Error getting existence result", http.StatusInternalServerError)
-			return
+		if created {
+			metrics.Likes.Inc()
 		}
 
-		if count.(int64) == 0 {
-			http.Error(w, "User or Post not found", http.StatusNotFound)
-			return
-		}
 		w.WriteHeader(http.StatusCreated)
 		w.Write([]byte("Liked"))
 	}
@@ -621,263 +553,184 @@ func LikePostHandler(app *app.App) http.HandlerFunc {
 
 func DislikePostHandler(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		session := app.DB.NewSession(ctx, neo4j.SessionConfig{DatabaseName: "neo4j"})
-		defer session.Close(ctx)
+		ctx := r.Context()
 
-		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		id, ok := actingUser(w, r)
+		if !ok {
 			return
 		}
 
-		postId, err := strconv.ParseInt(chi.URLParam(r, "post-id"), 10, 64)
-		if err != nil {
-			http.Error(w, "Couldn't parse the url param", http.StatusInternalServerError)
+		postId, ok := pathID(w, r, "post-id")
+		if !ok {
 			return
 		}
 
-		res, err := session.Run(
-			ctx,
-			`MATCH (u:User)-[r:LIKED]->(p:Post)
-			 WHERE id(u) = $id AND id(p) = $postId
-			 DELETE r
-			 RETURN COUNT(r) as count`,
-			map[string]any{"id": id, "postId": postId},
-		)
-
-		if err != nil {
-			http.Error(w, "DB operation failed", http.StatusInternalServerError)
+		err := app.Posts.Unlike(ctx, id, postId)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(w, r, apierror.NotFound("User or Post not found"))
 			return
 		}
-
-		record, err := res.Single(ctx)
 		if err != nil {
-			http.Error(w, "Unexpected DB result", http.StatusNotFound)
+			apierror.Write(w, r, err)
 			return
 		}
 
-		count, ok := record.Get("count")
-		if !ok {
-			http.Error(w, "Error getting existence result", http.StatusInternalServerError)
-			return
-		}
-
-		if count.(int64) == 0 {
-			http.Error(w, "User or Post not found", http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Liked"))
-
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-func recordToJSON(ctx context.Context, w http.ResponseWriter, res neo4j.ResultWithContext) []byte {
-	record, err := res.Single(ctx)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return nil
-	}
-
-	resId, ok := record.Get("id")
+// Id do usuário autenticado pelo middleware auth.Authenticate
+func actingUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId, ok := auth.UserID(r.Context())
 	if !ok {
-		http.Error(w, "Error getting user ID", http.StatusInternalServerError)
-		return nil
+		apierror.Write(w, r, apierror.Unauthorized("Unauthorized"))
+		return "", false
 	}
+	return userId, true
+}
 
-	props, ok := record.Get("props")
-	if !ok {
-		http.Error(w, "Error getting user properties", http.StatusInternalServerError)
-		return nil
+// Id de usuário ou post vindo da rota. Um valor que nem é UUID responde 400 sem ir ao banco
+func pathID(w http.ResponseWriter, r *http.Request, param string) (string, bool) {
+	id := chi.URLParam(r, param)
+	if uuid.Validate(id) != nil {
+		apierror.Write(w, r, apierror.BadRequest("Invalid "+param))
+		return "", false
 	}
+	return id, true
+}
 
-	propsMap, ok := props.(map[string]any)
-	if !ok {
-		http.Error(w, "Error converting properties", http.StatusInternalServerError)
-		return nil
+// Responde 404/500 para erros do repositório. Retorna true se não houve erro
+func checkUserFound(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return false
 	}
-	propsMap["id"] = resId
-
-	follows, ok := record.Get("isFollower")
-	if ok {
-		propsMap["follows"] = follows
+	if err != nil {
+		apierror.Write(w, r, err)
+		return false
 	}
+	return true
+}
 
-	postCount, ok := record.Get("postCount")
-	if ok {
-		propsMap["postCount"] = postCount
-	}
+func userImageURL(userId string) string {
+	return "/user/" + userId + "/image"
+}
 
-	totalFollowers, ok := record.Get("totalFollowers")
-	if ok {
-		propsMap["followers"] = totalFollowers
+// Troca o caminho da imagem do usuário pela URL em que ela é servida
+func withImageURL(user models.User) models.User {
+	if user.Image != "" {
+		user.Image = userImageURL(user.Id)
 	}
+	return user
+}
 
-	totalFollowed, ok := record.Get("totalFollowed")
-	if ok {
-		propsMap["following"] = totalFollowed
+// Escolhe a representação do usuário: visão privada só para ele mesmo
+func userView(r *http.Request, user models.User) any {
+	if viewerId, ok := auth.UserID(r.Context()); ok && viewerId == user.Id {
+		return user.Private()
 	}
+	return user.Public()
+}
 
-	if propsMap["image"] != nil {
-		img, err := ImageToBase64(propsMap["image"].(string))
+func writeUser(w http.ResponseWriter, r *http.Request, user models.User) {
+	user = withImageURL(user)
 
-		if err != nil {
-			http.Error(w, "Error encoding user to JSON", http.StatusInternalServerError)
-			return nil
-		}
-
-		propsMap["image"] = img
-	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(userView(r, user))
+}
 
-	user, err := json.Marshal(propsMap)
-	if err != nil {
-		http.Error(w, "Error encoding user to JSON", http.StatusInternalServerError)
-		return nil
+// Lista vazia vira [], não é um erro
+func toPublicUsers(users []models.User) []models.PublicUser {
+	publicUsers := make([]models.PublicUser, 0, len(users))
+	for _, user := range users {
+		// listagens sempre mostram apenas o perfil público
+		publicUsers = append(publicUsers, withImageURL(user).Public())
 	}
-
-	return user
+	return publicUsers
 }
 
-func getIDsRecord(record *neo4j.Record, prop string) []int64 {
-	followsAny, ok := record.Get(prop)
-	var idList []int64
-	if ok {
-		// transforma de any para []any
-		follows, ok := followsAny.([]any)
-		if ok {
-			for _, f := range follows {
-				if id, ok := f.(int64); ok {
-					idList = append(idList, id)
-				}
-			}
+func UserImageHandler(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := pathID(w, r, "id")
+		if !ok {
+			return
 		}
-	}
 
-	return idList
-}
-
-func usersToJson(ctx context.Context, res neo4j.ResultWithContext, prop string) ([]byte, error, int) {
-	var users []models.User
-	for res.Next(ctx) {
-		record := res.Record()
-
-		node, ok := record.Get(prop)
-		if ok {
-			var user models.User
-			user_attr := node.(neo4j.Node).Props
-
-			imgPath := user_attr["image"]
-			if imgPath != nil {
-				// user com imagem
-				img, err := ImageToBase64(user_attr["image"].(string))
-
-				if err != nil {
-					return nil, errors.New("Error encoding user to JSON"), 500
-				}
-				user = models.User{
-					Id:       node.(neo4j.Node).GetId(),
-					Name:     user_attr["name"].(string),
-					Email:    user_attr["email"].(string),
-					Password: user_attr["password"].(string),
-					Image:    img,
-				}
-			} else {
-				// user sem imagem
-				user = models.User{
-					Id:       node.(neo4j.Node).GetId(),
-					Name:     user_attr["name"].(string),
-					Email:    user_attr["email"].(string),
-					Password: user_attr["password"].(string),
-				}
-			}
-
-			users = append(users, user)
+		user, err := app.Users.GetByID(r.Context(), id)
+		if !checkUserFound(w, r, err) {
+			return
 		}
-	}
 
-	if len(users) == 0 {
-		return nil, errors.New("Not Found"), 404
-	}
+		if user.Image == "" {
+			apierror.Write(w, r, apierror.NotFound("Image not found"))
+			return
+		}
 
-	usersJson, err := json.Marshal(users)
-	if err != nil {
-		return nil, errors.New("Error encoding users to JSON"), 500
+		writeImage(w, r, user.Image)
 	}
-
-	return usersJson, nil, 200
 }
 
-func ImageToBase64(imagePath string) (string, error) {
-	imageBytes, err := os.ReadFile(imagePath)
+// Responde com o conteúdo da imagem. O tipo vem dos primeiros bytes, já que a extensão
+// do arquivo salvo não garante o formato
+func writeImage(w http.ResponseWriter, r *http.Request, path string) {
+	imageBytes, err := readImage(r.Context(), path)
+	if errors.Is(err, fs.ErrNotExist) {
+		apierror.Write(w, r, apierror.NotFound("Image not found"))
+		return
+	}
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("Erro ao ler imagem %s: %v", imagePath, err))
+		apierror.Write(w, r, apierror.Internal("Failed to read image", err))
+		return
 	}
 
-	imageEncoded := base64.StdEncoding.EncodeToString(imageBytes)
-	return imageEncoded, nil
+	w.Header().Set("Content-Type", http.DetectContentType(imageBytes))
+	w.WriteHeader(http.StatusOK)
+	w.Write(imageBytes)
 }
 
-func createProfilePicture(userId int64, fileHeader *multipart.FileHeader) (string, error, int) {
-	if fileHeader.Size > (50 << 20) {
-		return "", errors.New("File too large"), 400
-	}
+func readImage(ctx context.Context, path string) (imageBytes []byte, err error) {
+	_, span := tracing.Start(ctx, "image.read", attribute.String("file.path", path))
+	defer func() { tracing.End(span, err) }()
 
-	file, err := fileHeader.Open()
+	imageBytes, err = os.ReadFile(path)
 	if err != nil {
-		return "", errors.New("Invalid image"), 400
+		return nil, err
 	}
-	defer file.Close()
 
-	dirPath := fmt.Sprintf("imgs/user-%d/profile-picture/", userId)
-	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
-		return "", errors.New("Failed to save profile picture"), 500
-	}
-
-	filename := dirPath + "profile-picture.png"
-
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return "", errors.New("Failed to save image"), 500
-	}
-	defer outFile.Close()
+	metrics.ImageBytesRead.Add(float64(len(imageBytes)))
+	span.SetAttributes(attribute.Int("file.size", len(imageBytes)))
+	return imageBytes, nil
+}
 
-	_, err = io.Copy(outFile, file)
-	if err != nil {
-		return "", errors.New("Failed to save image"), 500
-	}
+// Apaga o diretório de imagens com tudo o que houver dentro
+func removeImages(ctx context.Context, dir string) (err error) {
+	_, span := tracing.Start(ctx, "image.remove", attribute.String("file.path", dir))
+	defer func() { tracing.End(span, err) }()
 
-	return filename, nil, 200
+	return os.RemoveAll(dir)
 }
 
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
-	return string(bytes), err
+func createProfilePicture(ctx context.Context, uploads config.UploadConfig, userId string, fileHeader *multipart.FileHeader) (string, error) {
+	dir := filepath.Join(userImgsDir(uploads, userId), "profile-picture")
+	return saveImage(ctx, fileHeader, dir, "profile-picture.png")
 }
 
-func checkPasswordHash(password string, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+func hashPassword(password string, cost int) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	return string(bytes), err
 }
 
-func emailExists(ctx context.Context, session neo4j.SessionWithContext, email string) (bool, error) {
-	res, err := session.Run(ctx, `
-		MATCH (u:User {email: $email})
-		RETURN COUNT(u) > 0 AS exists
-	`, map[string]any{"email": email})
+func rehashPassword(ctx context.Context, app *app.App, userId string, password string) error {
+	hashedPassword, err := hashPassword(password, app.Passwords.Cost)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	record, err := res.Single(ctx)
-	if err != nil {
-		return false, err
-	}
-
-	exists, ok := record.Get("exists")
-	if !ok {
-		return false, nil
-	}
+	return app.Users.SetPassword(ctx, userId, hashedPassword)
+}
 
-	return exists.(bool), nil
+func checkPasswordHash(password string, hash string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return err == nil
 }
@@ -0,0 +1,593 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"main.go/auth"
+	"main.go/models"
+	"main.go/repository"
+	"main.go/validation"
+)
+
+func TestResponsesNeverExposeAnotherUsersPasswordOrEmail(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	aliceToken := s.login(alice.Email)
+	bobToken := s.login(bob.Email)
+
+	// para as listas de seguidores e posts não ficarem vazias
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/"+bob.Id, aliceToken), http.StatusCreated)
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/"+alice.Id, bobToken), http.StatusCreated)
+	s.createPost(alice.Id, aliceToken, "post da alice")
+
+	paths := []string{
+		"/user/",
+		"/user/" + alice.Id,
+		"/user/" + alice.Id + "/followers",
+		"/user/" + alice.Id + "/following",
+		"/user/" + bob.Id + "/followers",
+		"/user/profile/" + alice.Id,
+		"/posts/",
+		"/posts/" + alice.Id,
+	}
+	viewers := []struct {
+		name  string
+		token string
+	}{
+		{"anonymous", ""},
+		{"another user", bobToken},
+	}
+
+	for _, viewer := range viewers {
+		for _, path := range paths {
+			t.Run(viewer.name+" GET "+path, func(t *testing.T) {
+				rec := s.request(http.MethodGet, path, viewer.token)
+
+				want := http.StatusOK
+				if viewer.token == "" && strings.HasPrefix(path, "/user/profile/") {
+					want = http.StatusUnauthorized
+				}
+				expectStatus(t, rec, want)
+
+				body := rec.Body.String()
+				if strings.Contains(body, alice.Password) {
+					t.Errorf("response contains the password hash: %s", body)
+				}
+				if strings.Contains(body, `"password"`) {
+					t.Errorf("response contains a password field: %s", body)
+				}
+				if strings.Contains(body, alice.Email) {
+					t.Errorf("response contains another user's email: %s", body)
+				}
+			})
+		}
+	}
+}
+
+func TestOwnerSeesOwnEmailButNeverThePasswordHash(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+
+	rec := s.request(http.MethodGet, "/user/"+alice.Id, token)
+	expectStatus(t, rec, http.StatusOK)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, alice.Email) {
+		t.Errorf("private view without the email: %s", body)
+	}
+	if strings.Contains(body, alice.Password) {
+		t.Errorf("response contains the password hash: %s", body)
+	}
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	if alice.Password == testPassword || alice.Role != "user" {
+		t.Fatalf("user saved with password %q and role %q", alice.Password, alice.Role)
+	}
+
+	rec := s.requestForm(http.MethodPost, "/user/", "", map[string]string{
+		"name":     "Other Alice",
+		"email":    alice.Email,
+		"password": testPassword,
+	}, nil)
+	expectStatus(t, rec, http.StatusConflict)
+
+	rec = s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": alice.Email, "password": "wrong password"})
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	rec = s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": alice.Email, "password": testPassword})
+	expectStatus(t, rec, http.StatusOK)
+
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	decode(t, rec, &tokens)
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("login without tokens: %s", rec.Body)
+	}
+
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", tokens.AccessToken), http.StatusOK)
+}
+
+func TestSignupReportsEveryInvalidField(t *testing.T) {
+	s := newTestServer(t)
+
+	image := bytes.Repeat([]byte("x"), int(s.app.Config.Uploads.MaxFileSize)+1)
+	rec := s.requestForm(http.MethodPost, "/user/", "", map[string]string{
+		"name":     strings.Repeat("a", validation.NameMaxLength+1),
+		"email":    "not an email",
+		"password": "short",
+	}, map[string][][]byte{"image": {image}})
+
+	if fields := invalidFields(t, rec); !slices.Equal(fields, []string{"name", "email", "password", "image"}) {
+		t.Errorf("invalid fields = %v, want name, email, password and image", fields)
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	token := s.login(alice.Email)
+
+	follows := s.metric("follows_total")
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/"+bob.Id, ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/"+bob.Id, token), http.StatusCreated)
+	// seguir de novo não é erro, mas não conta outro follow
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/"+bob.Id, token), http.StatusCreated)
+	if got := s.metric("follows_total") - follows; got != 1 {
+		t.Errorf("follows_total grew by %v, want 1", got)
+	}
+	expectStatus(t, s.request(http.MethodPost, "/user/follow/00000000-0000-0000-0000-000000000000", token), http.StatusNotFound)
+
+	var followers struct {
+		Items []struct {
+			Id string `json:"id"`
+		} `json:"items"`
+	}
+	rec := s.request(http.MethodGet, "/user/"+bob.Id+"/followers", "")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &followers)
+	if len(followers.Items) != 1 || followers.Items[0].Id != alice.Id {
+		t.Fatalf("followers of bob = %s", rec.Body)
+	}
+
+	var profile struct {
+		Follows   bool  `json:"follows"`
+		Followers int64 `json:"followers"`
+	}
+	rec = s.request(http.MethodGet, "/user/profile/"+bob.Id, token)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &profile)
+	if !profile.Follows || profile.Followers != 1 {
+		t.Errorf("profile of bob = %s", rec.Body)
+	}
+
+	expectStatus(t, s.request(http.MethodPost, "/user/unfollow/"+bob.Id, token), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodPost, "/user/unfollow/"+bob.Id, token), http.StatusNotFound)
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	carol := s.signup("Carol", "carol@example.com")
+	aliceToken := s.login(alice.Email)
+	bobToken := s.login(bob.Email)
+	adminToken := s.loginWithRole(carol, auth.RoleAdmin)
+	s.createPost(alice.Id, aliceToken, "post da alice", []byte("image"))
+
+	expectStatus(t, s.request(http.MethodDelete, "/user/"+alice.Id, ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodDelete, "/user/"+alice.Id, bobToken), http.StatusForbidden)
+
+	expectStatus(t, s.request(http.MethodDelete, "/user/"+alice.Id, aliceToken), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodGet, "/user/"+alice.Id, ""), http.StatusNotFound)
+	// a sessão foi junto com a conta
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", aliceToken), http.StatusUnauthorized)
+
+	rec := s.request(http.MethodGet, "/posts/", "")
+	expectStatus(t, rec, http.StatusOK)
+	if strings.Contains(rec.Body.String(), "post da alice") {
+		t.Errorf("posts of a deleted user still listed: %s", rec.Body)
+	}
+	if _, err := os.Stat(filepath.Join(s.app.Config.Uploads.Dir, "user-"+alice.Id)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("images of a deleted user left on disk: %v", err)
+	}
+
+	// admin apaga a conta de outro usuário
+	expectStatus(t, s.request(http.MethodDelete, "/user/"+bob.Id, adminToken), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodDelete, "/user/"+bob.Id, adminToken), http.StatusNotFound)
+}
+
+func TestOnlyOwnerOrAdminUpdatesUser(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	aliceToken := s.login(alice.Email)
+	bobToken := s.login(bob.Email)
+
+	update := map[string]string{"id": alice.Id, "name": "Alice Silva", "email": alice.Email}
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/", bobToken, update), http.StatusForbidden)
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/", aliceToken, update), http.StatusOK)
+
+	adminToken := s.loginWithRole(bob, auth.RoleAdmin)
+	update["name"] = "Alice S."
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/", adminToken, update), http.StatusOK)
+}
+
+func TestOnlyAdminChangesRoles(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	aliceToken := s.login(alice.Email)
+
+	body := map[string]string{"role": "admin"}
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/"+alice.Id+"/role", aliceToken, body), http.StatusForbidden)
+
+	adminToken := s.loginWithRole(bob, auth.RoleAdmin)
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/"+alice.Id+"/role", adminToken, map[string]string{"role": "superuser"}), http.StatusUnprocessableEntity)
+	// o papel moderator foi removido
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/"+alice.Id+"/role", adminToken, map[string]string{"role": "moderator"}), http.StatusUnprocessableEntity)
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/"+alice.Id+"/role", adminToken, body), http.StatusNoContent)
+
+	user, err := s.app.Users.GetByID(context.Background(), alice.Id)
+	if err != nil || user.Role != string(auth.RoleAdmin) {
+		t.Errorf("role after change = %q, %v", user.Role, err)
+	}
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", aliceToken), http.StatusUnauthorized)
+}
+
+func TestDemotedAdminLosesRightsImmediately(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	carol := s.signup("Carol", "carol@example.com")
+	aliceToken := s.login(alice.Email)
+	bobToken := s.loginWithRole(bob, auth.RoleAdmin)
+	carolToken := s.loginWithRole(carol, auth.RoleAdmin)
+
+	postId := s.createPost(alice.Id, aliceToken, "post")
+
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/"+bob.Id+"/role", carolToken, map[string]string{"role": "user"}), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+postId, bobToken), http.StatusUnauthorized)
+
+	bobToken = s.login(bob.Email)
+	expectStatus(t, s.request(http.MethodDelete, "/posts/"+postId, bobToken), http.StatusForbidden)
+}
+
+func TestRevokedSessionRejectsAccessToken(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+	other := s.login(alice.Email)
+
+	expectStatus(t, s.request(http.MethodPost, "/user/logout", token), http.StatusNoContent)
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", token), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", other), http.StatusOK)
+}
+
+func TestExpiredSessionRejectsAccessTokenAndIsPruned(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	alice := s.signup("Alice", "alice@example.com")
+
+	past := time.Now().Add(-time.Hour)
+	expired := models.Session{
+		Id:         "expired-session",
+		Family:     "expired-session",
+		TokenHash:  auth.HashToken("expired-refresh"),
+		CreatedAt:  past.Add(-time.Hour),
+		LastUsedAt: past.Add(-time.Hour),
+		ExpiresAt:  past,
+	}
+	if err := s.app.Sessions.Create(ctx, alice.Id, expired); err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := s.app.Tokens.Issue(alice.Id, expired.Id, auth.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", token), http.StatusUnauthorized)
+
+	// o próximo login apaga a sessão expirada
+	s.login(alice.Email)
+	if _, err := s.app.Sessions.GetByTokenHash(ctx, expired.TokenHash); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expired session still stored: %v", err)
+	}
+}
+
+func TestOnlyAdminLooksUpUsersByEmail(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+	path := "/user/email/" + alice.Email
+
+	expectStatus(t, s.request(http.MethodGet, path, ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodGet, path, s.login(alice.Email)), http.StatusForbidden)
+	expectStatus(t, s.request(http.MethodGet, "/user/email/nobody@example.com", s.login(bob.Email)), http.StatusForbidden)
+
+	adminToken := s.loginWithRole(bob, auth.RoleAdmin)
+	expectStatus(t, s.request(http.MethodGet, path, adminToken), http.StatusOK)
+	expectStatus(t, s.request(http.MethodGet, "/user/email/nobody@example.com", adminToken), http.StatusNotFound)
+}
+
+func TestSignupVerificationCanBeResent(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	if alice.Verified || alice.PendingEmail != "" {
+		t.Fatalf("after signup verified = %v, pending_email = %q", alice.Verified, alice.PendingEmail)
+	}
+	token := s.login(alice.Email)
+	// o envio do cadastro termina antes, para o reenvio ser o último email
+	s.lastMail()
+
+	expectStatus(t, s.request(http.MethodPost, "/user/email/resend", ""), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodPost, "/user/email/resend", token), http.StatusAccepted)
+
+	msg := s.lastMail()
+	if msg.To != alice.Email {
+		t.Fatalf("resent to %q, want %q", msg.To, alice.Email)
+	}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/email/confirm", "", map[string]string{"token": mailCode(msg)}), http.StatusNoContent)
+
+	user, err := s.app.Users.GetByID(context.Background(), alice.Id)
+	if err != nil || !user.Verified || user.PendingEmail != "" {
+		t.Fatalf("after confirm verified = %v, pending_email = %q, %v", user.Verified, user.PendingEmail, err)
+	}
+	expectStatus(t, s.request(http.MethodPost, "/user/email/resend", token), http.StatusConflict)
+}
+
+func TestEmailChangeConfirmation(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+	s.lastMail()
+
+	change := map[string]string{"name": alice.Name, "email": "alice@new.example.com"}
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/", token, change), http.StatusOK)
+	s.lastMail()
+
+	expectEmail := func(want string) {
+		t.Helper()
+		user, err := s.app.Users.GetByID(ctx, alice.Id)
+		if err != nil || user.Email != want {
+			t.Fatalf("email = %q, %v; want %q", user.Email, err, want)
+		}
+	}
+
+	confirm := func(token string) *httptest.ResponseRecorder {
+		return s.requestJSON(http.MethodPost, "/user/email/confirm", "", map[string]string{"token": token})
+	}
+
+	// token qualquer
+	expectStatus(t, confirm("not-a-real-token"), http.StatusBadRequest)
+	expectEmail(alice.Email)
+
+	// token expirado
+	now := time.Now()
+	err := s.app.AccountTokens.CreateEmailVerification(ctx, alice.Id, "alice@expired.example.com", auth.HashToken("expired-token"), now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, confirm("expired-token"), http.StatusBadRequest)
+	expectEmail(alice.Email)
+
+	// o endereço passou a ser de outra conta enquanto a troca estava pendente
+	expectStatus(t, s.requestJSON(http.MethodPut, "/user/", token, change), http.StatusOK)
+	code := mailCode(s.lastMail())
+	s.signup("Other", "alice@new.example.com")
+	expectStatus(t, confirm(code), http.StatusConflict)
+	expectEmail(alice.Email)
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	const newPassword = "N3w!Passw0rdLong"
+
+	alice := s.signup("Alice", "alice@example.com")
+	before := s.loginWith(alice.Email, testPassword)
+	s.lastMail()
+
+	// a resposta não diz se a conta existe
+	unknown := s.requestJSON(http.MethodPost, "/user/password/forgot", "", map[string]string{"email": "nobody@example.com"})
+	known := s.requestJSON(http.MethodPost, "/user/password/forgot", "", map[string]string{"email": alice.Email})
+	expectStatus(t, unknown, http.StatusAccepted)
+	expectStatus(t, known, http.StatusAccepted)
+	if unknown.Body.String() != known.Body.String() {
+		t.Errorf("bodies differ: %q and %q", unknown.Body, known.Body)
+	}
+
+	msg := s.lastMail()
+	if msg.To != alice.Email {
+		t.Fatalf("reset sent to %q", msg.To)
+	}
+	reset := map[string]string{"token": mailCode(msg), "password": newPassword}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/password/reset", "", reset), http.StatusNoContent)
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/password/reset", "", reset), http.StatusBadRequest)
+
+	rec := s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": alice.Email, "password": testPassword})
+	expectStatus(t, rec, http.StatusUnauthorized)
+	s.loginWith(alice.Email, newPassword)
+
+	// as sessões de antes da troca não valem mais
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", before.AccessToken), http.StatusUnauthorized)
+	expectStatus(t, s.refresh(before.RefreshToken), http.StatusUnauthorized)
+
+	past := time.Now().Add(-2 * time.Hour)
+	if _, err := s.app.AccountTokens.CreatePasswordReset(ctx, alice.Email, auth.HashToken("expired-token"), past, past.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	expired := map[string]string{"token": "expired-token", "password": testPassword}
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/password/reset", "", expired), http.StatusBadRequest)
+}
+
+func TestChangePassword(t *testing.T) {
+	s := newTestServer(t)
+	const newPassword = "N3w!Passw0rdLong"
+
+	alice := s.signup("Alice", "alice@example.com")
+	current := s.loginWith(alice.Email, testPassword)
+	other := s.loginWith(alice.Email, testPassword)
+
+	change := func(currentPassword string, newPassword string) *httptest.ResponseRecorder {
+		body := map[string]string{"current_password": currentPassword, "new_password": newPassword}
+		return s.requestJSON(http.MethodPut, "/user/password", current.AccessToken, body)
+	}
+
+	expectStatus(t, change("wrong password", newPassword), http.StatusForbidden)
+	if fields := invalidFields(t, change(testPassword, "short")); !slices.Equal(fields, []string{"new_password"}) {
+		t.Errorf("invalid fields = %v, want [new_password]", fields)
+	}
+
+	expectStatus(t, change(testPassword, newPassword), http.StatusNoContent)
+	s.loginWith(alice.Email, newPassword)
+
+	// só a familia de sessões de quem trocou a senha continua valendo
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", other.AccessToken), http.StatusUnauthorized)
+	expectStatus(t, s.refresh(other.RefreshToken), http.StatusUnauthorized)
+	expectStatus(t, s.request(http.MethodGet, "/user/sessions", current.AccessToken), http.StatusOK)
+	expectStatus(t, s.refresh(current.RefreshToken), http.StatusOK)
+}
+
+func TestChangePasswordThrottlesWrongCurrentPassword(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	token := s.login(alice.Email)
+	body := map[string]string{"current_password": "wrong password", "new_password": "N3w!Passw0rdLong"}
+
+	// as primeiras falhas passam, depois o email fica bloqueado como no login
+	for range auth.DefaultEmailGuardPolicy.FreeAttempts + 1 {
+		expectStatus(t, s.requestJSON(http.MethodPut, "/user/password", token, body), http.StatusForbidden)
+	}
+	rec := s.requestJSON(http.MethodPut, "/user/password", token, body)
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+}
+
+func TestLoginRehashesPasswordsWithOutdatedCost(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	s.app.Passwords.Cost = bcrypt.MinCost + 1
+	s.login(alice.Email)
+
+	user, err := s.app.Users.GetByID(context.Background(), alice.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(user.Password)); cost != bcrypt.MinCost+1 {
+		t.Errorf("cost after login = %d, want %d", cost, bcrypt.MinCost+1)
+	}
+	s.login(alice.Email)
+}
+
+func TestRepeatedLoginFailuresAreThrottled(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	wrong := map[string]string{"email": alice.Email, "password": "wrong password"}
+
+	for range auth.DefaultEmailGuardPolicy.FreeAttempts + 1 {
+		expectStatus(t, s.requestJSON(http.MethodPost, "/user/login", "", wrong), http.StatusUnauthorized)
+	}
+
+	// nem a senha certa passa enquanto durar o atraso
+	right := map[string]string{"email": alice.Email, "password": testPassword}
+	rec := s.requestJSON(http.MethodPost, "/user/login", "", right)
+	expectStatus(t, rec, http.StatusTooManyRequests)
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestLoginAttemptsShowOnlyTheCallersHistory(t *testing.T) {
+	s := newTestServer(t)
+
+	alice := s.signup("Alice", "alice@example.com")
+	bob := s.signup("Bob", "bob@example.com")
+
+	expectStatus(t, s.requestJSON(http.MethodPost, "/user/login", "", map[string]string{"email": alice.Email, "password": "wrong password"}), http.StatusUnauthorized)
+	aliceToken := s.login(alice.Email)
+	bobToken := s.login(bob.Email)
+
+	history := func(token string) []models.LoginAttempt {
+		t.Helper()
+		rec := s.request(http.MethodGet, "/user/login-attempts", token)
+		expectStatus(t, rec, http.StatusOK)
+		var attempts []models.LoginAttempt
+		decode(t, rec, &attempts)
+		return attempts
+	}
+
+	expectStatus(t, s.request(http.MethodGet, "/user/login-attempts", ""), http.StatusUnauthorized)
+
+	// mais recentes primeiro
+	if attempts := history(aliceToken); len(attempts) != 2 || !attempts[0].Success || attempts[1].Success {
+		t.Errorf("alice's attempts = %+v, want a success after a failure", attempts)
+	}
+	if attempts := history(bobToken); len(attempts) != 1 || !attempts[0].Success {
+		t.Errorf("bob's attempts = %+v, want only his login", attempts)
+	}
+}
+
+func TestLoginAttemptHistoryIsCapped(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+
+	alice := s.signup("Alice", "alice@example.com")
+	start := time.Now().Add(-time.Hour)
+	for i := range repository.LoginAttemptHistory + 5 {
+		attempt := models.LoginAttempt{IP: "203.0.113.7", CreatedAt: start.Add(time.Duration(i) * time.Second)}
+		if err := s.app.Users.RecordLoginAttempt(ctx, alice.Id, attempt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attempts, err := s.app.Users.LoginAttempts(ctx, alice.Id, repository.LoginAttemptHistory+5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != repository.LoginAttemptHistory {
+		t.Fatalf("%d attempts stored, want %d", len(attempts), repository.LoginAttemptHistory)
+	}
+	// as mais antigas é que foram apagadas
+	oldest := start.Add(5 * time.Second)
+	if got := attempts[len(attempts)-1].CreatedAt; !got.Equal(oldest) {
+		t.Errorf("oldest attempt kept = %s, want %s", got, oldest)
+	}
+}
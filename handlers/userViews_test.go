@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main.go/auth"
+	"main.go/models"
+)
+
+const passwordHash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+
+func TestUserViewsNeverExposeThePasswordOrAnotherUsersEmail(t *testing.T) {
+	user := models.User{Id: "user-1", Name: "Alice", Email: "alice@example.com", Password: passwordHash, Role: "user"}
+
+	viewers := []struct {
+		name      string
+		ctx       context.Context
+		seesEmail bool
+	}{
+		{"anonymous", context.Background(), false},
+		{"another user", auth.WithClaims(context.Background(), auth.Claims{UserID: "user-2", Role: auth.RoleUser}), false},
+		{"owner", auth.WithClaims(context.Background(), auth.Claims{UserID: "user-1", Role: auth.RoleUser}), true},
+	}
+
+	for _, viewer := range viewers {
+		t.Run(viewer.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user/user-1", nil).WithContext(viewer.ctx)
+			rec := httptest.NewRecorder()
+			writeUser(rec, req, user)
+
+			body := rec.Body.String()
+			if strings.Contains(body, passwordHash) || strings.Contains(body, `"password"`) {
+				t.Errorf("response contains the password: %s", body)
+			}
+			if got := strings.Contains(body, user.Email); got != viewer.seesEmail {
+				t.Errorf("email in response = %v, want %v: %s", got, viewer.seesEmail, body)
+			}
+		})
+	}
+}
+
+func TestUserListsOnlyContainPublicProfiles(t *testing.T) {
+	users := []models.PublicUser{
+		models.User{Id: "user-1", Name: "Alice", Email: "alice@example.com", Password: passwordHash}.Public(),
+		models.User{Id: "user-2", Name: "Bob", Email: "bob@example.com", Password: passwordHash}.Public(),
+	}
+
+	body, err := json.Marshal(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, leaked := range []string{passwordHash, `"password"`, "alice@example.com", "bob@example.com", `"email"`} {
+		if strings.Contains(string(body), leaked) {
+			t.Errorf("list contains %q: %s", leaked, body)
+		}
+	}
+}
+
+func TestUserNeverSerializesThePasswordHash(t *testing.T) {
+	body, err := json.Marshal(models.User{Id: "user-1", Name: "Alice", Password: passwordHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(body), passwordHash) {
+		t.Errorf("models.User serialized the password hash: %s", body)
+	}
+}
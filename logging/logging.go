@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"main.go/config"
+)
+
+type ctxKey struct{}
+
+// Dados da requisição que vão em todas as linhas de log feitas com o seu contexto.
+// É um ponteiro para o middleware de autenticação, que roda depois, poder completar
+type requestInfo struct {
+	mu     sync.Mutex
+	id     string
+	userId string
+}
+
+// Logger em JSON (ou texto, com LOG_FORMAT=text) que inclui request_id e user_id
+// quando o log é feito com o contexto de uma requisição
+func New(cfg config.LogConfig, out io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL inválido: %q (use debug, info, warn ou error)", cfg.Level)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(out, options)
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT desconhecido: %q (use json ou text)", cfg.Format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+func withRequest(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &requestInfo{id: id})
+}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(ctxKey{}).(*requestInfo)
+	return info
+}
+
+// Id da requisição colocado por RequestID, ou "" fora de uma requisição
+func RequestIDFrom(ctx context.Context) string {
+	info := infoFrom(ctx)
+	if info == nil {
+		return ""
+	}
+	return info.id
+}
+
+// Associa o usuário autenticado à requisição, para os logs seguintes e o log de acesso
+func SetUserID(ctx context.Context, userId string) {
+	info := infoFrom(ctx)
+	if info == nil {
+		return
+	}
+
+	info.mu.Lock()
+	info.userId = userId
+	info.mu.Unlock()
+}
+
+func (info *requestInfo) attrs() []slog.Attr {
+	info.mu.Lock()
+	defer info.mu.Unlock()
+
+	attrs := []slog.Attr{slog.String("request_id", info.id)}
+	if info.userId != "" {
+		attrs = append(attrs, slog.String("user_id", info.userId))
+	}
+	return attrs
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if info := infoFrom(ctx); info != nil {
+		record.AddAttrs(info.attrs()...)
+	}
+	// liga a linha de log ao trace da requisição, quando há um sendo gravado
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() && span.IsSampled() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
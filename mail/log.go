@@ -0,0 +1,66 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Não envia nada, apenas escreve os emails em um writer. Para desenvolvimento local
+type LogMailer struct {
+	mu   sync.Mutex
+	out  io.Writer
+	file *os.File
+}
+
+func NewFileMailer(path string) (*LogMailer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open mail file %s: %v", path, err)
+	}
+	return &LogMailer{out: file, file: file}, nil
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.out, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+func (m *LogMailer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file = nil
+	return err
+}
+
+// Para MAIL_DRIVER=log: registra o email no log da aplicação em vez de enviar. O corpo
+// leva códigos de uso único (recuperação de senha, confirmação de email), então só vai
+// para o log com LOG_LEVEL=debug
+type SlogMailer struct{}
+
+func NewSlogMailer() *SlogMailer {
+	return &SlogMailer{}
+}
+
+func (m *SlogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "email not sent (MAIL_DRIVER=log)", "to", msg.To, "subject", msg.Subject)
+	slog.DebugContext(ctx, "email body", "to", msg.To, "body", msg.Body)
+	return nil
+}
+
+func (m *SlogMailer) Close() error {
+	return nil
+}
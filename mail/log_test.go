@@ -0,0 +1,44 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"main.go/mail"
+)
+
+func TestSlogMailerLogsBodyOnlyAtDebug(t *testing.T) {
+	msg := mail.Message{To: "alice@example.com", Subject: "Recuperação de senha", Body: "código: 123456"}
+
+	tests := []struct {
+		level    slog.Level
+		wantBody bool
+	}{
+		{slog.LevelInfo, false},
+		{slog.LevelDebug, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: tt.level})))
+			defer slog.SetDefault(previous)
+
+			if err := mail.NewSlogMailer().Send(context.Background(), msg); err != nil {
+				t.Fatal(err)
+			}
+
+			logged := out.String()
+			if !strings.Contains(logged, msg.To) {
+				t.Errorf("recipient missing from log: %s", logged)
+			}
+			if got := strings.Contains(logged, "123456"); got != tt.wantBody {
+				t.Errorf("body logged = %v, want %v: %s", got, tt.wantBody, logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"main.go/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	// libera o que a implementação mantém aberto. Chamado ao encerrar o servidor
+	Close() error
+}
+
+// Escolhe a implementação pelo driver: "log" (padrão), "file" ou "smtp"
+func InitMailer(cfg config.MailConfig) (Mailer, error) {
+	switch driver := cfg.Driver; driver {
+	case "", "log":
+		return NewSlogMailer(), nil
+	case "file":
+		return NewFileMailer(cfg.File)
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPMailer struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{addr: net.JoinHostPort(host, port), host: host, from: from, auth: auth}
+}
+
+// Como smtp.SendMail (STARTTLS quando o servidor oferece), mas respeitando o prazo de ctx
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) (err error) {
+	defer func() {
+		// o erro de conexão fechada sozinho não diz que o motivo foi o prazo
+		if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+			err = fmt.Errorf("%w: %v", ctxErr, err)
+		}
+	}()
+
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("invalid mail header")
+	}
+
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.from, msg.To, msg.Subject, msg.Body)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	// o pacote smtp não recebe contexto: fechar a conexão interrompe o que estiver em andamento
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// Cada envio abre e fecha a própria conexão, então não há nada para liberar
+func (m *SMTPMailer) Close() error {
+	return nil
+}
@@ -2,34 +2,186 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"flag"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"main.go/app"
+	"main.go/auth"
+	"main.go/clientip"
+	"main.go/config"
 	"main.go/db"
+	"main.go/logging"
+	"main.go/mail"
+	"main.go/metrics"
+	"main.go/ratelimit"
 	"main.go/routes"
+	"main.go/tracing"
 )
 
 func main() {
-	driver, err := db.InitDB()
+	configPath := flag.String("config", "", "arquivo YAML de configuração (padrão: CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "mostra a configuração efetiva, sem os segredos, e sai")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		panic(err)
+		fatal("invalid configuration", err)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fatal("could not print configuration", err)
+		}
+		return
 	}
 
-	defer driver.Close(context.Background())
+	logger, err := logging.New(cfg.Log, os.Stdout)
+	if err != nil {
+		fatal("invalid log configuration", err)
+	}
+	// também redireciona o pacote log, usado por bibliotecas
+	slog.SetDefault(logger)
+
+	// "go run main.go migrate [--dry-run]" só aplica as migrações e sai
+	if flag.Arg(0) == "migrate" {
+		migrate(cfg, flag.Args()[1:])
+		return
+	}
+
+	shutdownTracing, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		fatal("could not initialize tracing", err)
+	}
+
+	repositories, closeStorage, err := db.InitStorage(cfg.Database)
+	if err != nil {
+		fatal("could not initialize storage", err)
+	}
 
-	app := &app.App{DB: driver}
+	repositories = metrics.InstrumentRepositories(repositories)
+
+	tokens, err := auth.InitTokens(cfg.Auth)
+	if err != nil {
+		fatal("could not initialize tokens", err)
+	}
+
+	totp := auth.NewTOTP(cfg.Auth.TOTPIssuer, auth.SystemClock{})
+
+	mailer, err := mail.InitMailer(cfg.Mail)
+	if err != nil {
+		fatal("could not initialize mailer", err)
+	}
+
+	loginGuard := auth.NewLoginGuard(auth.DefaultEmailGuardPolicy, auth.DefaultIPGuardPolicy, auth.SystemClock{})
+
+	app := &app.App{
+		Config:       cfg,
+		Repositories: repositories,
+		Tokens:       tokens,
+		TOTP:         totp,
+		LoginGuard:   loginGuard,
+		RateLimiter:  ratelimit.NewMemoryStore(auth.SystemClock{}),
+		Passwords:    auth.InitPasswords(cfg.Password),
+		Mailer:       mailer,
+		StartedAt:    time.Now(),
+	}
+
+	clientIPs, err := clientip.New(cfg.Server.TrustedProxies)
+	if err != nil {
+		fatal("invalid trusted proxies", err)
+	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(logging.RequestID, clientIPs.Middleware, tracing.Middleware, logging.AccessLog, metrics.Middleware)
 	routes.RegisterRoutes(r, app)
 
-	log.Println("Servidor rodando na porta 3000")
-	err = http.ListenAndServe(":3000", r)
-	if err != nil {
-		panic(fmt.Errorf("Não foi possível inicializar o servidor, erro: %v",err))
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+
+	if err := serve(server, cfg.Server, &app.Draining); err != nil {
+		fatal("server failed", err)
+	}
+
+	// só depois que nenhuma requisição pode mais usá-los
+	app.Mails.Wait()
+	if err := mailer.Close(); err != nil {
+		slog.Error("failed to close mailer", "error", err)
+	}
+	closeStorage()
+
+	// envia os spans que ainda estão no buffer
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	slog.Info("server stopped")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// Atende até receber SIGINT/SIGTERM. Então liga draining (o /readyz passa a falhar), espera
+// ShutdownDelay e para de aceitar conexões, aguardando até ShutdownTimeout pelas requisições
+// em andamento. As que passarem disso são interrompidas
+func serve(server *http.Server, cfg config.ServerConfig, draining *atomic.Bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// um segundo sinal encerra na hora
+	stop()
+	draining.Store(true)
+
+	if cfg.ShutdownDelay > 0 {
+		slog.Info("shutdown signal received, readiness now failing", "delay", cfg.ShutdownDelay.String())
+		time.Sleep(cfg.ShutdownDelay)
+	}
+	slog.Info("draining in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("requests did not finish in time, closing connections", "error", err)
+		return server.Close()
+	}
+	return nil
+}
+
+func migrate(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "apenas mostra as migrações pendentes")
+	flags.Parse(args)
+
+	if err := db.Migrate(cfg.Database, *dryRun); err != nil {
+		fatal("migration failed", err)
 	}
 }
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registro próprio em vez do global, para /metrics mostrar só o que é registrado aqui
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+	httpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Requisições HTTP atendidas, por rota do chi e status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Tempo para atender cada requisição HTTP, por rota do chi.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	storageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "storage_operation_duration_seconds",
+		Help:    "Tempo de cada operação dos repositórios (Neo4j ou memória).",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"repository", "operation"})
+
+	storageErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_operation_errors_total",
+		Help: "Operações dos repositórios que falharam, sem contar ErrNotFound e ErrConflict.",
+	}, []string{"repository", "operation"})
+)
+
+var (
+	// O driver do Neo4j (v5) não expõe estatísticas das conexões: quantas estão ociosas,
+	// em uso ou quanto se espera por uma. Exportá-las fica para quando o driver as expuser;
+	// por ora só medimos as sessões que abrimos, cada uma segurando uma conexão
+	Neo4jSessionsInUse = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "neo4j_sessions_in_use",
+		Help: "Sessões do Neo4j abertas pelo servidor no momento, cada uma segurando uma conexão. Contadas pela aplicação, não pelo driver.",
+	})
+
+	Neo4jMaxConnections = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "neo4j_configured_max_connections",
+		Help: "Limite de conexões passado ao driver (DB_MAX_POOL_SIZE). É configuração, não uma estatística; compare com neo4j_sessions_in_use.",
+	})
+
+	ImageBytesWritten = factory.NewCounter(prometheus.CounterOpts{
+		Name: "image_bytes_written_total",
+		Help: "Bytes de imagens gravados em disco.",
+	})
+
+	ImageBytesRead = factory.NewCounter(prometheus.CounterOpts{
+		Name: "image_bytes_read_total",
+		Help: "Bytes de imagens lidos do disco para as respostas.",
+	})
+
+	Signups = factory.NewCounter(prometheus.CounterOpts{
+		Name: "signups_total",
+		Help: "Usuários cadastrados.",
+	})
+
+	PostsCreated = factory.NewCounter(prometheus.CounterOpts{
+		Name: "posts_created_total",
+		Help: "Posts criados.",
+	})
+
+	Follows = factory.NewCounter(prometheus.CounterOpts{
+		Name: "follows_total",
+		Help: "Usuários seguidos.",
+	})
+
+	Likes = factory.NewCounter(prometheus.CounterOpts{
+		Name: "likes_total",
+		Help: "Posts curtidos.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Rota /metrics no formato do Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
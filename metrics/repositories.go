@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+// Envolve os repositórios medindo a duração e os erros de cada operação
+func InstrumentRepositories(repos repository.Repositories) repository.Repositories {
+	return repository.Repositories{
+		Users:         users{repos.Users},
+		Posts:         posts{repos.Posts},
+		Sessions:      sessions{repos.Sessions},
+		AccountTokens: accountTokens{repos.AccountTokens},
+		Health:        repos.Health,
+	}
+}
+
+// ErrNotFound e ErrConflict são respostas normais (ex: email já usado), não falhas do banco
+func observe(repo string, operation string, start time.Time, err *error) {
+	storageDuration.WithLabelValues(repo, operation).Observe(time.Since(start).Seconds())
+
+	if *err != nil && !errors.Is(*err, repository.ErrNotFound) && !errors.Is(*err, repository.ErrConflict) {
+		storageErrors.WithLabelValues(repo, operation).Inc()
+	}
+}
+
+type users struct {
+	repository.UserRepository
+}
+
+func (r users) Create(ctx context.Context, user models.User) (_ string, err error) {
+	defer observe("users", "Create", time.Now(), &err)
+	return r.UserRepository.Create(ctx, user)
+}
+
+func (r users) GetByID(ctx context.Context, id string) (_ models.User, err error) {
+	defer observe("users", "GetByID", time.Now(), &err)
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func (r users) GetByEmail(ctx context.Context, email string) (_ models.User, err error) {
+	defer observe("users", "GetByEmail", time.Now(), &err)
+	return r.UserRepository.GetByEmail(ctx, email)
+}
+
+func (r users) List(ctx context.Context, page repository.Page) (_ []models.User, err error) {
+	defer observe("users", "List", time.Now(), &err)
+	return r.UserRepository.List(ctx, page)
+}
+
+func (r users) UpdateName(ctx context.Context, id string, name string) (_ models.User, err error) {
+	defer observe("users", "UpdateName", time.Now(), &err)
+	return r.UserRepository.UpdateName(ctx, id, name)
+}
+
+func (r users) Delete(ctx context.Context, id string) (err error) {
+	defer observe("users", "Delete", time.Now(), &err)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r users) EmailExists(ctx context.Context, email string) (_ bool, err error) {
+	defer observe("users", "EmailExists", time.Now(), &err)
+	return r.UserRepository.EmailExists(ctx, email)
+}
+
+func (r users) EmailTakenByOther(ctx context.Context, email string, userId string) (_ bool, err error) {
+	defer observe("users", "EmailTakenByOther", time.Now(), &err)
+	return r.UserRepository.EmailTakenByOther(ctx, email, userId)
+}
+
+func (r users) Follow(ctx context.Context, userId string, otherId string) (_ bool, err error) {
+	defer observe("users", "Follow", time.Now(), &err)
+	return r.UserRepository.Follow(ctx, userId, otherId)
+}
+
+func (r users) Unfollow(ctx context.Context, userId string, otherId string) (err error) {
+	defer observe("users", "Unfollow", time.Now(), &err)
+	return r.UserRepository.Unfollow(ctx, userId, otherId)
+}
+
+func (r users) Followers(ctx context.Context, id string, page repository.Page) (_ []models.User, err error) {
+	defer observe("users", "Followers", time.Now(), &err)
+	return r.UserRepository.Followers(ctx, id, page)
+}
+
+func (r users) Following(ctx context.Context, id string, page repository.Page) (_ []models.User, err error) {
+	defer observe("users", "Following", time.Now(), &err)
+	return r.UserRepository.Following(ctx, id, page)
+}
+
+func (r users) Profile(ctx context.Context, id string, requesterId string) (_ models.User, _ repository.ProfileStats, err error) {
+	defer observe("users", "Profile", time.Now(), &err)
+	return r.UserRepository.Profile(ctx, id, requesterId)
+}
+
+func (r users) SetRole(ctx context.Context, id string, role string) (err error) {
+	defer observe("users", "SetRole", time.Now(), &err)
+	return r.UserRepository.SetRole(ctx, id, role)
+}
+
+func (r users) SetPassword(ctx context.Context, id string, passwordHash string) (err error) {
+	defer observe("users", "SetPassword", time.Now(), &err)
+	return r.UserRepository.SetPassword(ctx, id, passwordHash)
+}
+
+func (r users) StartTOTPEnrollment(ctx context.Context, id string, secret string) (_ models.User, err error) {
+	defer observe("users", "StartTOTPEnrollment", time.Now(), &err)
+	return r.UserRepository.StartTOTPEnrollment(ctx, id, secret)
+}
+
+func (r users) EnableTOTP(ctx context.Context, id string, secret string, step int64, recoveryHashes []string) (err error) {
+	defer observe("users", "EnableTOTP", time.Now(), &err)
+	return r.UserRepository.EnableTOTP(ctx, id, secret, step, recoveryHashes)
+}
+
+func (r users) UseTOTPStep(ctx context.Context, id string, step int64) (_ bool, err error) {
+	defer observe("users", "UseTOTPStep", time.Now(), &err)
+	return r.UserRepository.UseTOTPStep(ctx, id, step)
+}
+
+func (r users) DisableTOTP(ctx context.Context, id string) (err error) {
+	defer observe("users", "DisableTOTP", time.Now(), &err)
+	return r.UserRepository.DisableTOTP(ctx, id)
+}
+
+func (r users) UseRecoveryCode(ctx context.Context, id string, codeHash string) (_ bool, err error) {
+	defer observe("users", "UseRecoveryCode", time.Now(), &err)
+	return r.UserRepository.UseRecoveryCode(ctx, id, codeHash)
+}
+
+func (r users) RecordLoginAttempt(ctx context.Context, userId string, attempt models.LoginAttempt) (err error) {
+	defer observe("users", "RecordLoginAttempt", time.Now(), &err)
+	return r.UserRepository.RecordLoginAttempt(ctx, userId, attempt)
+}
+
+func (r users) LoginAttempts(ctx context.Context, userId string, limit int) (_ []models.LoginAttempt, err error) {
+	defer observe("users", "LoginAttempts", time.Now(), &err)
+	return r.UserRepository.LoginAttempts(ctx, userId, limit)
+}
+
+type posts struct {
+	repository.PostRepository
+}
+
+func (r posts) Create(ctx context.Context, userId string, post models.Post) (_ string, err error) {
+	defer observe("posts", "Create", time.Now(), &err)
+	return r.PostRepository.Create(ctx, userId, post)
+}
+
+func (r posts) Owner(ctx context.Context, postId string) (_ string, err error) {
+	defer observe("posts", "Owner", time.Now(), &err)
+	return r.PostRepository.Owner(ctx, postId)
+}
+
+func (r posts) Images(ctx context.Context, postId string) (_ []string, err error) {
+	defer observe("posts", "Images", time.Now(), &err)
+	return r.PostRepository.Images(ctx, postId)
+}
+
+func (r posts) Delete(ctx context.Context, postId string) (err error) {
+	defer observe("posts", "Delete", time.Now(), &err)
+	return r.PostRepository.Delete(ctx, postId)
+}
+
+func (r posts) List(ctx context.Context, page repository.Page) (_ []models.Post, err error) {
+	defer observe("posts", "List", time.Now(), &err)
+	return r.PostRepository.List(ctx, page)
+}
+
+func (r posts) ListByUser(ctx context.Context, userId string, page repository.Page) (_ []models.Post, err error) {
+	defer observe("posts", "ListByUser", time.Now(), &err)
+	return r.PostRepository.ListByUser(ctx, userId, page)
+}
+
+func (r posts) Like(ctx context.Context, userId string, postId string) (_ bool, err error) {
+	defer observe("posts", "Like", time.Now(), &err)
+	return r.PostRepository.Like(ctx, userId, postId)
+}
+
+func (r posts) Unlike(ctx context.Context, userId string, postId string) (err error) {
+	defer observe("posts", "Unlike", time.Now(), &err)
+	return r.PostRepository.Unlike(ctx, userId, postId)
+}
+
+type sessions struct {
+	repository.SessionRepository
+}
+
+func (r sessions) Create(ctx context.Context, userId string, session models.Session) (err error) {
+	defer observe("sessions", "Create", time.Now(), &err)
+	return r.SessionRepository.Create(ctx, userId, session)
+}
+
+func (r sessions) GetByTokenHash(ctx context.Context, tokenHash string) (_ models.Session, err error) {
+	defer observe("sessions", "GetByTokenHash", time.Now(), &err)
+	return r.SessionRepository.GetByTokenHash(ctx, tokenHash)
+}
+
+func (r sessions) Active(ctx context.Context, userId string, sessionId string, now time.Time) (_ bool, err error) {
+	defer observe("sessions", "Active", time.Now(), &err)
+	return r.SessionRepository.Active(ctx, userId, sessionId, now)
+}
+
+func (r sessions) Rotate(ctx context.Context, tokenHash string, next models.Session) (_ bool, err error) {
+	defer observe("sessions", "Rotate", time.Now(), &err)
+	return r.SessionRepository.Rotate(ctx, tokenHash, next)
+}
+
+func (r sessions) RevokeFamily(ctx context.Context, family string) (err error) {
+	defer observe("sessions", "RevokeFamily", time.Now(), &err)
+	return r.SessionRepository.RevokeFamily(ctx, family)
+}
+
+func (r sessions) Revoke(ctx context.Context, userId string, sessionId string) (err error) {
+	defer observe("sessions", "Revoke", time.Now(), &err)
+	return r.SessionRepository.Revoke(ctx, userId, sessionId)
+}
+
+func (r sessions) RevokeAllExcept(ctx context.Context, userId string, keepSessionId string) (err error) {
+	defer observe("sessions", "RevokeAllExcept", time.Now(), &err)
+	return r.SessionRepository.RevokeAllExcept(ctx, userId, keepSessionId)
+}
+
+func (r sessions) ListActive(ctx context.Context, userId string, now time.Time) (_ []models.Session, err error) {
+	defer observe("sessions", "ListActive", time.Now(), &err)
+	return r.SessionRepository.ListActive(ctx, userId, now)
+}
+
+type accountTokens struct {
+	repository.AccountTokenRepository
+}
+
+func (r accountTokens) CreatePasswordReset(ctx context.Context, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) (_ bool, err error) {
+	defer observe("accountTokens", "CreatePasswordReset", time.Now(), &err)
+	return r.AccountTokenRepository.CreatePasswordReset(ctx, email, tokenHash, createdAt, expiresAt)
+}
+
+func (r accountTokens) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (err error) {
+	defer observe("accountTokens", "ResetPassword", time.Now(), &err)
+	return r.AccountTokenRepository.ResetPassword(ctx, tokenHash, passwordHash, now)
+}
+
+func (r accountTokens) CreateEmailVerification(ctx context.Context, userId string, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) (err error) {
+	defer observe("accountTokens", "CreateEmailVerification", time.Now(), &err)
+	return r.AccountTokenRepository.CreateEmailVerification(ctx, userId, email, tokenHash, createdAt, expiresAt)
+}
+
+func (r accountTokens) ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) (err error) {
+	defer observe("accountTokens", "ConfirmEmail", time.Now(), &err)
+	return r.AccountTokenRepository.ConfirmEmail(ctx, tokenHash, now)
+}
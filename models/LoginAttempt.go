@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type LoginAttempt struct {
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
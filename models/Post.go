@@ -3,8 +3,8 @@ package models
 import "time"
 
 type Post struct {
-	Id          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
+	Id          string    `json:"id"`
+	UserID      string    `json:"user_id"`
 	UserName    string    `json:"username"`
 	Description string    `json:"description"`
 	Images      []string  `json:"images"`
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Session struct {
+	Id         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+
+	UserID string `json:"-"`
+	// sessões criadas por rotação do mesmo refresh token compartilham a familia
+	Family    string `json:"-"`
+	TokenHash string `json:"-"`
+	Revoked   bool   `json:"-"`
+	Rotated   bool   `json:"-"`
+}
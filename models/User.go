@@ -1,9 +1,65 @@
 package models
 
+import "time"
+
+// Usuário como salvo no banco. Nunca deve ser serializado direto em uma resposta,
+// use Public ou Private
 type User struct {
-	Id       int64  `json:"id"`
+	Id       string `json:"id"`
 	Name     string `json:"name"`
 	Email    string `json:"email"`
-	Password string `json:"password"`
+	Password string `json:"-"`
 	Image    string `json:"image,omitempty"`
+	Role     string `json:"role"`
+	// usado só para ordenar as listagens
+	CreatedAt time.Time `json:"created_at"`
+
+	Verified     bool   `json:"verified"`
+	PendingEmail string `json:"pending_email,omitempty"`
+
+	TOTPEnabled       bool     `json:"-"`
+	TOTPSecret        string   `json:"-"`
+	TOTPPendingSecret string   `json:"-"`
+	TOTPLastStep      int64    `json:"-"`
+	RecoveryCodes     []string `json:"-"`
+}
+
+// Perfil visível para qualquer pessoa
+type PublicUser struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+}
+
+// Visão do próprio usuário, inclui dados de contato e conta
+type PrivateUser struct {
+	PublicUser
+	Email        string `json:"email"`
+	Verified     bool   `json:"verified"`
+	PendingEmail string `json:"pending_email,omitempty"`
+	Role         string `json:"role"`
+}
+
+// Perfil com contadores. Email só é preenchido quando o usuário vê o próprio perfil
+type Profile struct {
+	PublicUser
+	Email     string `json:"email,omitempty"`
+	Follows   bool   `json:"follows"`
+	PostCount int64  `json:"postCount"`
+	Followers int64  `json:"followers"`
+	Following int64  `json:"following"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{Id: u.Id, Name: u.Name, Image: u.Image}
+}
+
+func (u User) Private() PrivateUser {
+	return PrivateUser{
+		PublicUser:   u.Public(),
+		Email:        u.Email,
+		Verified:     u.Verified,
+		PendingEmail: u.PendingEmail,
+		Role:         u.Role,
+	}
 }
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"main.go/auth"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	clock     auth.Clock
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func NewMemoryStore(clock auth.Clock) *MemoryStore {
+	return &MemoryStore{clock: clock, buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	capacity := float64(policy.Limit)
+	perToken := policy.Period / time.Duration(policy.Limit)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now, period: policy.Period}
+		s.buckets[key] = b
+	}
+
+	// reabastece proporcionalmente ao tempo desde a última requisição
+	elapsed := now.Sub(b.last)
+	b.tokens = math.Min(capacity, b.tokens+elapsed.Seconds()/perToken.Seconds())
+	b.last = now
+
+	result := Result{Limit: policy.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+
+	if now.Sub(s.lastPrune) > time.Minute {
+		s.prune(now)
+		s.lastPrune = now
+	}
+
+	return result, nil
+}
+
+// Baldes que já estariam cheios de novo equivalem a não ter balde nenhum
+func (s *MemoryStore) prune(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main.go/ratelimit"
+)
+
+// Relógio parado no instante escolhido pelo teste
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+// Uma ficha por segundo, até 3 de uma vez
+var testPolicy = ratelimit.Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+
+func take(t *testing.T, store *ratelimit.MemoryStore, key string) ratelimit.Result {
+	t.Helper()
+
+	result, err := store.Take(context.Background(), key, testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMemoryStoreAllowsTheBurstThenRefuses(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	store := ratelimit.NewMemoryStore(clock)
+
+	for i, remaining := range []int{2, 1, 0} {
+		result := take(t, store, "client")
+		if !result.Allowed || result.Remaining != remaining || result.Limit != testPolicy.Limit {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, remaining)
+		}
+	}
+
+	result := take(t, store, "client")
+	if result.Allowed {
+		t.Fatalf("request over the burst allowed: %+v", result)
+	}
+	if result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("retry after = %s, reset = %s, want 1s and 3s", result.RetryAfter, result.Reset)
+	}
+
+	// outro cliente tem o próprio balde
+	if result := take(t, store, "other"); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("other key = %+v, want a full bucket", result)
+	}
+}
+
+func TestMemoryStoreRefillsOverTime(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	store := ratelimit.NewMemoryStore(clock)
+
+	for range testPolicy.Limit {
+		take(t, store, "client")
+	}
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if result := take(t, store, "client"); result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("half a token later = %+v, want refused with 500ms to wait", result)
+	}
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if result := take(t, store, "client"); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("one token later = %+v, want allowed with 0 remaining", result)
+	}
+
+	// o balde não passa da capacidade, por mais tempo que fique parado
+	clock.now = clock.now.Add(time.Hour)
+	if result := take(t, store, "client"); !result.Allowed || result.Remaining != testPolicy.Limit-1 {
+		t.Errorf("after an hour = %+v, want a full bucket", result)
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main.go/apierror"
+	"main.go/auth"
+	"main.go/clientip"
+)
+
+// Identifica o cliente de uma requisição para escolher o balde
+type KeyFunc func(r *http.Request) string
+
+// Usuário autenticado quando houver, senão o IP do cliente
+func ByUserOrIP(r *http.Request) string {
+	if userId, ok := auth.UserID(r.Context()); ok {
+		return "user:" + userId
+	}
+
+	return "ip:" + clientip.FromRequest(r)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// Responde 429 quando o cliente passa do limite da política. Sempre envia os
+// headers RateLimit-Limit, RateLimit-Remaining e RateLimit-Reset
+func Middleware(store Store, policy Policy, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(r.Context(), policy.Name+":"+key(r), policy)
+			if err != nil {
+				// se o backend cair, melhor deixar passar do que derrubar a API
+				slog.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", strconv.Itoa(policy.Limit)+";w="+seconds(policy.Period))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", seconds(result.RetryAfter))
+				apierror.Write(w, r, apierror.TooManyRequests("Too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
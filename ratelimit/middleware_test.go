@@ -0,0 +1,75 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"main.go/auth"
+	"main.go/ratelimit"
+)
+
+func TestMiddlewareSetsRateLimitHeaders(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1700000000, 0)}
+	policy := ratelimit.Policy{Name: "test", Limit: 2, Period: time.Minute}
+	handler := ratelimit.Middleware(ratelimit.NewMemoryStore(clock), policy, ratelimit.ByUserOrIP)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	)
+
+	requests := []struct {
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{http.StatusNoContent, "1", "30", ""},
+		{http.StatusNoContent, "0", "60", ""},
+		{http.StatusTooManyRequests, "0", "60", "30"},
+	}
+
+	for i, want := range requests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		headers := rec.Header()
+		if rec.Code != want.status {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, want.status)
+		}
+		if got := headers.Get("RateLimit-Policy"); got != "2;w=60" {
+			t.Errorf("request %d: RateLimit-Policy = %q", i+1, got)
+		}
+		if got := headers.Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: RateLimit-Limit = %q", i+1, got)
+		}
+		if got := headers.Get("RateLimit-Remaining"); got != want.remaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i+1, got, want.remaining)
+		}
+		if got := headers.Get("RateLimit-Reset"); got != want.reset {
+			t.Errorf("request %d: RateLimit-Reset = %q, want %q", i+1, got, want.reset)
+		}
+		if got := headers.Get("Retry-After"); got != want.retryAfter {
+			t.Errorf("request %d: Retry-After = %q, want %q", i+1, got, want.retryAfter)
+		}
+	}
+}
+
+func TestByUserOrIP(t *testing.T) {
+	anonymous := httptest.NewRequest(http.MethodGet, "/", nil)
+	anonymous.RemoteAddr = "203.0.113.7:5000"
+
+	authenticated := anonymous.WithContext(auth.WithClaims(context.Background(), auth.Claims{UserID: "user-1", Role: auth.RoleUser}))
+
+	if got := ratelimit.ByUserOrIP(anonymous); got != "ip:203.0.113.7" {
+		t.Errorf("anonymous key = %q", got)
+	}
+	// o mesmo usuário conta no mesmo balde de qualquer IP
+	if got := ratelimit.ByUserOrIP(authenticated); got != "user:user-1" {
+		t.Errorf("authenticated key = %q", got)
+	}
+}
@@ -0,0 +1,29 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Balde de fichas: até Limit requisições de uma vez, reabastecido em Limit fichas por Period
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// tempo até o balde encher de novo
+	Reset time.Duration
+	// tempo até a próxima ficha quando a requisição foi recusada
+	RetryAfter time.Duration
+}
+
+// Backend que guarda os baldes. MemoryStore serve para uma instância só; com várias
+// instâncias use uma implementação compartilhada (ex: Redis)
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+type passwordReset struct {
+	tokenHash string
+	expiresAt time.Time
+	used      bool
+}
+
+type emailVerification struct {
+	tokenHash string
+	email     string
+	expiresAt time.Time
+}
+
+type AccountTokenRepository struct {
+	g *Graph
+}
+
+func (r *AccountTokenRepository) CreatePasswordReset(ctx context.Context, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user := r.g.findByEmail(email)
+	if user == nil {
+		return false, nil
+	}
+
+	// só o último token do usuário vale
+	r.g.passwordResets[user.Id] = &passwordReset{tokenHash: tokenHash, expiresAt: expiresAt}
+	return true, nil
+}
+
+func (r *AccountTokenRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	for userId, reset := range r.g.passwordResets {
+		if reset.tokenHash != tokenHash || reset.used || !reset.expiresAt.After(now) {
+			continue
+		}
+
+		reset.used = true
+		r.g.users[userId].Password = passwordHash
+		r.g.revokeSessions(func(session *models.Session) bool {
+			return session.UserID == userId
+		})
+		return nil
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *AccountTokenRepository) CreateEmailVerification(ctx context.Context, userId string, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user, ok := r.g.users[userId]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.PendingEmail = ""
+	if email != user.Email {
+		user.PendingEmail = email
+	}
+	r.g.emailVerifications[userId] = &emailVerification{tokenHash: tokenHash, email: email, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *AccountTokenRepository) ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	for userId, verification := range r.g.emailVerifications {
+		if verification.tokenHash != tokenHash || !verification.expiresAt.After(now) {
+			continue
+		}
+
+		// outra conta passou a usar o endereço enquanto a troca estava pendente
+		if other := r.g.findByEmail(verification.email); other != nil && other.Id != userId {
+			return repository.ErrConflict
+		}
+
+		user := r.g.users[userId]
+		user.Email = verification.email
+		user.Verified = true
+		user.PendingEmail = ""
+		delete(r.g.emailVerifications, userId)
+		return nil
+	}
+
+	return repository.ErrNotFound
+}
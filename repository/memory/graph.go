@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+// Grafo em memória com os mesmos nós e relações usados no Neo4j. Os dados somem
+// quando o processo termina; serve para testes e desenvolvimento local
+type Graph struct {
+	mu sync.RWMutex
+
+	users map[string]*models.User
+	posts map[string]*models.Post
+	// seguidor -> seguidos
+	follows map[string]map[string]bool
+	// usuário -> posts curtidos
+	likes         map[string]map[string]bool
+	loginAttempts map[string][]models.LoginAttempt
+
+	sessions map[string]*models.Session
+	// tokens de uso único, no máximo um de cada tipo por usuário
+	passwordResets     map[string]*passwordReset
+	emailVerifications map[string]*emailVerification
+}
+
+func New() *Graph {
+	return &Graph{
+		users:         map[string]*models.User{},
+		posts:         map[string]*models.Post{},
+		follows:       map[string]map[string]bool{},
+		likes:         map[string]map[string]bool{},
+		loginAttempts: map[string][]models.LoginAttempt{},
+
+		sessions:           map[string]*models.Session{},
+		passwordResets:     map[string]*passwordReset{},
+		emailVerifications: map[string]*emailVerification{},
+	}
+}
+
+func (g *Graph) Users() *UserRepository {
+	return &UserRepository{g}
+}
+
+func (g *Graph) Posts() *PostRepository {
+	return &PostRepository{g}
+}
+
+func (g *Graph) Sessions() *SessionRepository {
+	return &SessionRepository{g}
+}
+
+func (g *Graph) AccountTokens() *AccountTokenRepository {
+	return &AccountTokenRepository{g}
+}
+
+// Todos os repositórios apontando para o mesmo grafo
+func (g *Graph) Repositories() repository.Repositories {
+	return repository.Repositories{
+		Users:         g.Users(),
+		Posts:         g.Posts(),
+		Sessions:      g.Sessions(),
+		AccountTokens: g.AccountTokens(),
+		Health:        g,
+	}
+}
+
+// Em memória está sempre disponível
+func (g *Graph) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (g *Graph) findByEmail(email string) *models.User {
+	for _, id := range sortedKeys(g.users) {
+		if g.users[id].Email == email {
+			return g.users[id]
+		}
+	}
+	return nil
+}
+
+// Cópias, para quem recebe não alterar o grafo sem passar pelo lock
+func copyUser(user *models.User) models.User {
+	copied := *user
+	copied.RecoveryCodes = slices.Clone(user.RecoveryCodes)
+	return copied
+}
+
+func (g *Graph) copyPost(post *models.Post) models.Post {
+	copied := *post
+	copied.Images = slices.Clone(post.Images)
+	if owner, ok := g.users[post.UserID]; ok {
+		copied.UserName = owner.Name
+		copied.UserImage = owner.Image
+	}
+	return copied
+}
+
+// Ordena como as listagens do Neo4j (created_at e id decrescentes) e recorta a página
+func paginate[T any](items []T, page repository.Page, cursor func(T) repository.Cursor) []T {
+	slices.SortFunc(items, func(a, b T) int {
+		ca, cb := cursor(a), cursor(b)
+		if c := cb.CreatedAt.Compare(ca.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(cb.ID, ca.ID)
+	})
+
+	result := make([]T, 0, min(len(items), page.Limit))
+	for _, item := range items {
+		if len(result) == page.Limit {
+			break
+		}
+		if c := cursor(item); page.After.Precedes(c.CreatedAt, c.ID) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+var (
+	_ repository.UserRepository         = (*UserRepository)(nil)
+	_ repository.PostRepository         = (*PostRepository)(nil)
+	_ repository.SessionRepository      = (*SessionRepository)(nil)
+	_ repository.AccountTokenRepository = (*AccountTokenRepository)(nil)
+	_ repository.HealthChecker          = (*Graph)(nil)
+)
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+type PostRepository struct {
+	g *Graph
+}
+
+func (g *Graph) deletePost(postId string) {
+	for _, liked := range g.likes {
+		delete(liked, postId)
+	}
+	delete(g.posts, postId)
+}
+
+func (r *PostRepository) Create(ctx context.Context, userId string, post models.Post) (string, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if _, ok := r.g.users[userId]; !ok {
+		return "", repository.ErrNotFound
+	}
+
+	created := post
+	if created.Id == "" {
+		created.Id = repository.NewID()
+	}
+	if _, ok := r.g.posts[created.Id]; ok {
+		return "", repository.ErrConflict
+	}
+	created.UserID = userId
+	created.Images = slices.Clone(post.Images)
+	r.g.posts[created.Id] = &created
+
+	return created.Id, nil
+}
+
+func (r *PostRepository) Owner(ctx context.Context, postId string) (string, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	post, ok := r.g.posts[postId]
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	return post.UserID, nil
+}
+
+func (r *PostRepository) Images(ctx context.Context, postId string) ([]string, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	post, ok := r.g.posts[postId]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return slices.Clone(post.Images), nil
+}
+
+func (r *PostRepository) Delete(ctx context.Context, postId string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if _, ok := r.g.posts[postId]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.g.deletePost(postId)
+	return nil
+}
+
+func (r *PostRepository) list(page repository.Page, match func(post *models.Post) bool) []models.Post {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, id := range sortedKeys(r.g.posts) {
+		if post := r.g.posts[id]; match(post) {
+			posts = append(posts, r.g.copyPost(post))
+		}
+	}
+	return paginate(posts, page, repository.PostCursor)
+}
+
+func (r *PostRepository) List(ctx context.Context, page repository.Page) ([]models.Post, error) {
+	return r.list(page, func(post *models.Post) bool { return true }), nil
+}
+
+func (r *PostRepository) ListByUser(ctx context.Context, userId string, page repository.Page) ([]models.Post, error) {
+	return r.list(page, func(post *models.Post) bool { return post.UserID == userId }), nil
+}
+
+func (r *PostRepository) Like(ctx context.Context, userId string, postId string) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	_, okUser := r.g.users[userId]
+	_, okPost := r.g.posts[postId]
+	if !okUser || !okPost {
+		return false, repository.ErrNotFound
+	}
+
+	if r.g.likes[userId] == nil {
+		r.g.likes[userId] = map[string]bool{}
+	}
+	created := !r.g.likes[userId][postId]
+	r.g.likes[userId][postId] = true
+	return created, nil
+}
+
+func (r *PostRepository) Unlike(ctx context.Context, userId string, postId string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if !r.g.likes[userId][postId] {
+		return repository.ErrNotFound
+	}
+
+	delete(r.g.likes[userId], postId)
+	return nil
+}
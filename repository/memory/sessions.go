@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+type SessionRepository struct {
+	g *Graph
+}
+
+func (r *SessionRepository) Create(ctx context.Context, userId string, session models.Session) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if _, ok := r.g.users[userId]; !ok {
+		return repository.ErrNotFound
+	}
+
+	r.g.pruneSessions(userId, session.LastUsedAt)
+
+	created := session
+	created.UserID = userId
+	r.g.sessions[created.Id] = &created
+	return nil
+}
+
+// Apaga as sessões expiradas do usuário
+func (g *Graph) pruneSessions(userId string, now time.Time) {
+	for id, session := range g.sessions {
+		if session.UserID == userId && !session.ExpiresAt.After(now) {
+			delete(g.sessions, id)
+		}
+	}
+}
+
+func (g *Graph) sessionByTokenHash(tokenHash string) *models.Session {
+	for _, session := range g.sessions {
+		if session.TokenHash == tokenHash {
+			return session
+		}
+	}
+	return nil
+}
+
+func (g *Graph) revokeSessions(match func(session *models.Session) bool) {
+	for _, session := range g.sessions {
+		if match(session) {
+			session.Revoked = true
+		}
+	}
+}
+
+func (r *SessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (models.Session, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	session := r.g.sessionByTokenHash(tokenHash)
+	if session == nil {
+		return models.Session{}, repository.ErrNotFound
+	}
+	return *session, nil
+}
+
+func (r *SessionRepository) Rotate(ctx context.Context, tokenHash string, next models.Session) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	session := r.g.sessionByTokenHash(tokenHash)
+	if session == nil || session.Rotated || session.Revoked {
+		return false, nil
+	}
+
+	session.Rotated = true
+	r.g.pruneSessions(session.UserID, next.LastUsedAt)
+
+	created := next
+	created.UserID = session.UserID
+	r.g.sessions[created.Id] = &created
+	return true, nil
+}
+
+func (r *SessionRepository) RevokeFamily(ctx context.Context, family string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	r.g.revokeSessions(func(session *models.Session) bool {
+		return session.Family == family
+	})
+	return nil
+}
+
+func (r *SessionRepository) Active(ctx context.Context, userId string, sessionId string, now time.Time) (bool, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	session, ok := r.g.sessions[sessionId]
+	return ok && session.UserID == userId && !session.Revoked && session.ExpiresAt.After(now), nil
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, userId string, sessionId string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	current, ok := r.g.sessions[sessionId]
+	if !ok || current.UserID != userId {
+		return repository.ErrNotFound
+	}
+
+	r.g.revokeSessions(func(session *models.Session) bool {
+		return session.UserID == userId && session.Family == current.Family
+	})
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllExcept(ctx context.Context, userId string, keepSessionId string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	family := ""
+	if current, ok := r.g.sessions[keepSessionId]; ok && current.UserID == userId {
+		family = current.Family
+	}
+
+	r.g.revokeSessions(func(session *models.Session) bool {
+		return session.UserID == userId && (family == "" || session.Family != family)
+	})
+	return nil
+}
+
+func (r *SessionRepository) ListActive(ctx context.Context, userId string, now time.Time) ([]models.Session, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range r.g.sessions {
+		if session.UserID == userId && !session.Revoked && !session.Rotated && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b models.Session) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	return sessions, nil
+}
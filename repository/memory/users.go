@@ -0,0 +1,338 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"main.go/models"
+	"main.go/repository"
+)
+
+type UserRepository struct {
+	g *Graph
+}
+
+func (r *UserRepository) Create(ctx context.Context, user models.User) (string, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	// mesmo comportamento da constraint de email único do Neo4j
+	if r.g.findByEmail(user.Email) != nil {
+		return "", repository.ErrConflict
+	}
+
+	created := user
+	if created.Id == "" {
+		created.Id = repository.NewID()
+	}
+	if _, ok := r.g.users[created.Id]; ok {
+		return "", repository.ErrConflict
+	}
+	created.Verified = false
+	r.g.users[created.Id] = &created
+
+	return created.Id, nil
+}
+
+// Executa fn com o usuário travado para escrita
+func (r *UserRepository) update(id string, fn func(user *models.User)) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user, ok := r.g.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	fn(user)
+	return nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (models.User, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	user, ok := r.g.users[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	user := r.g.findByEmail(email)
+	if user == nil {
+		return models.User{}, repository.ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (r *UserRepository) List(ctx context.Context, page repository.Page) ([]models.User, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	users := make([]models.User, 0, len(r.g.users))
+	for _, id := range sortedKeys(r.g.users) {
+		users = append(users, copyUser(r.g.users[id]))
+	}
+	return paginate(users, page, repository.UserCursor), nil
+}
+
+func (r *UserRepository) UpdateName(ctx context.Context, id string, name string) (models.User, error) {
+	var updated models.User
+	err := r.update(id, func(user *models.User) {
+		user.Name = name
+		updated = copyUser(user)
+	})
+	return updated, err
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if _, ok := r.g.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+
+	for postId, post := range r.g.posts {
+		if post.UserID == id {
+			r.g.deletePost(postId)
+		}
+	}
+
+	for _, followed := range r.g.follows {
+		delete(followed, id)
+	}
+	delete(r.g.follows, id)
+	delete(r.g.likes, id)
+	delete(r.g.loginAttempts, id)
+	delete(r.g.passwordResets, id)
+	delete(r.g.emailVerifications, id)
+	for sessionId, session := range r.g.sessions {
+		if session.UserID == id {
+			delete(r.g.sessions, sessionId)
+		}
+	}
+	delete(r.g.users, id)
+
+	return nil
+}
+
+func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	return r.g.findByEmail(email) != nil, nil
+}
+
+func (r *UserRepository) EmailTakenByOther(ctx context.Context, email string, userId string) (bool, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	for _, user := range r.g.users {
+		if user.Email == email && user.Id != userId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *UserRepository) Follow(ctx context.Context, userId string, otherId string) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	_, okA := r.g.users[userId]
+	_, okB := r.g.users[otherId]
+	if !okA || !okB {
+		return false, repository.ErrNotFound
+	}
+
+	if r.g.follows[userId] == nil {
+		r.g.follows[userId] = map[string]bool{}
+	}
+	created := !r.g.follows[userId][otherId]
+	r.g.follows[userId][otherId] = true
+	return created, nil
+}
+
+func (r *UserRepository) Unfollow(ctx context.Context, userId string, otherId string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if !r.g.follows[userId][otherId] {
+		return repository.ErrNotFound
+	}
+
+	delete(r.g.follows[userId], otherId)
+	return nil
+}
+
+func (r *UserRepository) Followers(ctx context.Context, id string, page repository.Page) ([]models.User, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	users := []models.User{}
+	if _, ok := r.g.users[id]; !ok {
+		return users, nil
+	}
+
+	for _, followerId := range sortedKeys(r.g.follows) {
+		if r.g.follows[followerId][id] {
+			users = append(users, copyUser(r.g.users[followerId]))
+		}
+	}
+	return paginate(users, page, repository.UserCursor), nil
+}
+
+func (r *UserRepository) Following(ctx context.Context, id string, page repository.Page) ([]models.User, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	users := []models.User{}
+	for _, followedId := range sortedKeys(r.g.follows[id]) {
+		users = append(users, copyUser(r.g.users[followedId]))
+	}
+	return paginate(users, page, repository.UserCursor), nil
+}
+
+func (r *UserRepository) Profile(ctx context.Context, id string, requesterId string) (models.User, repository.ProfileStats, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	user, ok := r.g.users[id]
+	if !ok {
+		return models.User{}, repository.ProfileStats{}, repository.ErrNotFound
+	}
+
+	stats := repository.ProfileStats{
+		Follows:   r.g.follows[requesterId][id],
+		Following: int64(len(r.g.follows[id])),
+	}
+
+	for _, followed := range r.g.follows {
+		if followed[id] {
+			stats.Followers++
+		}
+	}
+
+	for _, post := range r.g.posts {
+		if post.UserID == id {
+			stats.PostCount++
+		}
+	}
+
+	return copyUser(user), stats, nil
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, id string, role string) error {
+	return r.update(id, func(user *models.User) {
+		user.Role = role
+	})
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, id string, passwordHash string) error {
+	return r.update(id, func(user *models.User) {
+		user.Password = passwordHash
+	})
+}
+
+func (r *UserRepository) StartTOTPEnrollment(ctx context.Context, id string, secret string) (models.User, error) {
+	var updated models.User
+	err := r.update(id, func(user *models.User) {
+		if !user.TOTPEnabled {
+			user.TOTPPendingSecret = secret
+		}
+		updated = copyUser(user)
+	})
+	return updated, err
+}
+
+func (r *UserRepository) EnableTOTP(ctx context.Context, id string, secret string, step int64, recoveryHashes []string) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user, ok := r.g.users[id]
+	if !ok || user.TOTPPendingSecret != secret {
+		return repository.ErrNotFound
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = slices.Clone(recoveryHashes)
+	user.TOTPPendingSecret = ""
+	return nil
+}
+
+func (r *UserRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user, ok := r.g.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error) {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	user, ok := r.g.users[id]
+	if !ok || !user.TOTPEnabled {
+		return false, nil
+	}
+
+	idx := slices.Index(user.RecoveryCodes, codeHash)
+	if idx < 0 {
+		return false, nil
+	}
+
+	user.RecoveryCodes = slices.Delete(user.RecoveryCodes, idx, idx+1)
+	return true, nil
+}
+
+func (r *UserRepository) DisableTOTP(ctx context.Context, id string) error {
+	return r.update(id, func(user *models.User) {
+		user.TOTPEnabled = false
+		user.TOTPSecret = ""
+		user.RecoveryCodes = nil
+	})
+}
+
+func (r *UserRepository) RecordLoginAttempt(ctx context.Context, userId string, attempt models.LoginAttempt) error {
+	r.g.mu.Lock()
+	defer r.g.mu.Unlock()
+
+	if _, ok := r.g.users[userId]; !ok {
+		return nil
+	}
+
+	history := append(r.g.loginAttempts[userId], attempt)
+	if len(history) > repository.LoginAttemptHistory {
+		history = slices.Clone(history[len(history)-repository.LoginAttemptHistory:])
+	}
+	r.g.loginAttempts[userId] = history
+	return nil
+}
+
+func (r *UserRepository) LoginAttempts(ctx context.Context, userId string, limit int) ([]models.LoginAttempt, error) {
+	r.g.mu.RLock()
+	defer r.g.mu.RUnlock()
+
+	history := r.g.loginAttempts[userId]
+
+	// mais recentes primeiro
+	attempts := make([]models.LoginAttempt, 0, min(limit, len(history)))
+	for i := len(history) - 1; i >= 0 && len(attempts) < limit; i-- {
+		attempts = append(attempts, history[i])
+	}
+	return attempts, nil
+}
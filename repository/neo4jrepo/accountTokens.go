@@ -0,0 +1,138 @@
+package neo4jrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"main.go/repository"
+)
+
+type AccountTokenRepository struct {
+	client
+}
+
+func (r *AccountTokenRepository) CreatePasswordReset(ctx context.Context, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) (bool, error) {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User {email: $email})
+		 OPTIONAL MATCH (u)-[:HAS_RESET_TOKEN]->(old:PasswordReset)
+		 DETACH DELETE old
+		 WITH DISTINCT u
+		 CREATE (u)-[:HAS_RESET_TOKEN]->(:PasswordReset {
+			token_hash: $hash,
+			created_at: $createdAt,
+			expires_at: $expiresAt,
+			used: false
+		 })
+		 RETURN COUNT(u) AS count`,
+		map[string]any{
+			"email":     email,
+			"hash":      tokenHash,
+			"createdAt": formatTime(createdAt),
+			"expiresAt": formatTime(expiresAt),
+		},
+	)
+	return count > 0, err
+}
+
+func (r *AccountTokenRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) error {
+	// marca o token como usado e troca a senha na mesma query, assim ele só vale uma vez
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User)-[:HAS_RESET_TOKEN]->(t:PasswordReset {token_hash: $hash})
+		 WHERE t.used = false AND t.expires_at > $now
+		 SET t.used = true, u.password = $password
+		 WITH u
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(s:Session)
+		 SET s.revoked = true
+		 RETURN COUNT(DISTINCT u) AS count`,
+		map[string]any{"hash": tokenHash, "now": formatTime(now), "password": passwordHash},
+	)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AccountTokenRepository) CreateEmailVerification(ctx context.Context, userId string, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) error {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $userId
+		 OPTIONAL MATCH (u)-[:HAS_EMAIL_VERIFICATION]->(old:EmailVerification)
+		 DETACH DELETE old
+		 WITH DISTINCT u
+		 SET u.pending_email = CASE WHEN u.email = $email THEN null ELSE $email END
+		 CREATE (u)-[:HAS_EMAIL_VERIFICATION]->(:EmailVerification {
+			token_hash: $hash,
+			email: $email,
+			created_at: $createdAt,
+			expires_at: $expiresAt
+		 })
+		 RETURN COUNT(u) AS count`,
+		map[string]any{
+			"userId":    userId,
+			"email":     email,
+			"hash":      tokenHash,
+			"createdAt": formatTime(createdAt),
+			"expiresAt": formatTime(expiresAt),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AccountTokenRepository) ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) error {
+	params := map[string]any{"hash": tokenHash, "now": formatTime(now)}
+
+	// checagem e troca na mesma transação. O COUNT agrupa por u e v para que um token
+	// inválido ou expirado não retorne nenhuma linha
+	return r.write(ctx, func(ctx context.Context, tx neo4j.ManagedTransaction) error {
+		records, err := collect(
+			ctx,
+			tx,
+			`MATCH (u:User)-[:HAS_EMAIL_VERIFICATION]->(v:EmailVerification {token_hash: $hash})
+			 WHERE v.expires_at > $now
+			 OPTIONAL MATCH (other:User {email: v.email})
+			 WHERE other <> u
+			 WITH u, v, COUNT(other) AS others
+			 RETURN others > 0 AS taken`,
+			params,
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return repository.ErrNotFound
+		}
+
+		// outra conta passou a usar o endereço enquanto a troca estava pendente
+		taken, _ := records[0].Get("taken")
+		if taken.(bool) {
+			return repository.ErrConflict
+		}
+
+		_, err = collect(
+			ctx,
+			tx,
+			`MATCH (u:User)-[:HAS_EMAIL_VERIFICATION]->(v:EmailVerification {token_hash: $hash})
+			 WHERE v.expires_at > $now
+			 SET u.email = v.email, u.verified = true
+			 REMOVE u.pending_email
+			 DETACH DELETE v`,
+			params,
+		)
+		return err
+	})
+}
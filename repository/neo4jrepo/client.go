@@ -0,0 +1,238 @@
+package neo4jrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"main.go/metrics"
+	"main.go/repository"
+	"main.go/tracing"
+)
+
+type client struct {
+	driver   neo4j.DriverWithContext
+	database string
+	timeout  time.Duration
+}
+
+// Repositórios que guardam os dados no Neo4j. Cada transação tem no máximo queryTimeout
+// (zero deixa só o prazo do contexto recebido)
+func New(driver neo4j.DriverWithContext, database string, queryTimeout time.Duration) repository.Repositories {
+	c := client{driver: driver, database: database, timeout: queryTimeout}
+
+	return repository.Repositories{
+		Users:         &UserRepository{c},
+		Posts:         &PostRepository{c},
+		Sessions:      &SessionRepository{c},
+		AccountTokens: &AccountTokenRepository{c},
+		Health:        c,
+	}
+}
+
+// Confere se o Neo4j responde, com o mesmo limite de tempo das queries
+func (c client) Ping(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	return translateError(ctx, c.driver.VerifyConnectivity(ctx))
+}
+
+// Roda a query numa transação de escrita gerenciada, que o driver repete em erros transitórios
+func (c client) run(ctx context.Context, cypher string, params map[string]any) ([]*neo4j.Record, error) {
+	var records []*neo4j.Record
+	err := c.write(ctx, func(ctx context.Context, tx neo4j.ManagedTransaction) error {
+		var err error
+		records, err = collect(ctx, tx, cypher, params)
+		return err
+	})
+	return records, err
+}
+
+// Como run, para consultas que não alteram dados
+func (c client) read(ctx context.Context, cypher string, params map[string]any) ([]*neo4j.Record, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	session, closeSession := c.session(ctx)
+	defer closeSession()
+
+	records, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return collect(ctx, tx, cypher, params)
+	}, c.txConfig)
+	if err != nil {
+		return nil, translateError(ctx, err)
+	}
+	return records.([]*neo4j.Record), nil
+}
+
+// Executa fn numa única transação de escrita: ou todas as queries valem, ou nenhuma.
+// fn pode ser chamada mais de uma vez, então não deve ter efeitos fora do banco, e deve
+// usar o contexto recebido, que carrega o timeout da query
+func (c client) write(ctx context.Context, fn func(ctx context.Context, tx neo4j.ManagedTransaction) error) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	session, closeSession := c.session(ctx)
+	defer closeSession()
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return nil, fn(ctx, tx)
+	}, c.txConfig)
+	return translateError(ctx, err)
+}
+
+// Cada sessão segura uma conexão enquanto está aberta
+func (c client) session(ctx context.Context) (neo4j.SessionWithContext, func()) {
+	session := c.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: c.database})
+	metrics.Neo4jSessionsInUse.Inc()
+
+	return session, func() {
+		session.Close(ctx)
+		metrics.Neo4jSessionsInUse.Dec()
+	}
+}
+
+func (c client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// O mesmo limite vai para o servidor, que aborta a transação mesmo se a conexão ficar presa
+func (c client) txConfig(config *neo4j.TransactionConfig) {
+	if c.timeout > 0 {
+		config.Timeout = c.timeout
+	}
+}
+
+// Cada query vira um span; se o driver repetir a transação, cada tentativa aparece no trace
+func collect(ctx context.Context, tx neo4j.ManagedTransaction, cypher string, params map[string]any) (records []*neo4j.Record, err error) {
+	ctx, span := tracing.Start(ctx, "neo4j.query", semconv.DBSystemNeo4j, semconv.DBQueryText(strings.TrimSpace(cypher)))
+	defer func() { tracing.End(span, err) }()
+
+	res, err := tx.Run(ctx, cypher, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.Collect(ctx)
+}
+
+// Violações de constraint (ex: email único) viram repository.ErrConflict. Se o contexto
+// acabou (timeout ou cliente desconectado) o erro passa a carregar context.DeadlineExceeded
+// ou context.Canceled, que o driver nem sempre repassa
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) && neo4jErr.Code == "Neo.ClientError.Schema.ConstraintValidationFailed" {
+		return fmt.Errorf("%w: %v", repository.ErrConflict, err)
+	}
+	return err
+}
+
+// Para queries que retornam uma única linha com a coluna "count"
+func (c client) count(ctx context.Context, cypher string, params map[string]any) (int64, error) {
+	return countOf(c.run(ctx, cypher, params))
+}
+
+// Para MERGE de relação que retorna a coluna "created". Sem linhas, um dos nós não existe
+func (c client) merge(ctx context.Context, cypher string, params map[string]any) (bool, error) {
+	records, err := c.run(ctx, cypher, params)
+	if err != nil {
+		return false, err
+	}
+	if len(records) == 0 {
+		return false, repository.ErrNotFound
+	}
+
+	created, _ := records[0].Get("created")
+	value, _ := created.(bool)
+	return value, nil
+}
+
+func countOf(records []*neo4j.Record, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	count, _ := records[0].Get("count")
+	value, _ := count.(int64)
+	return value, nil
+}
+
+// Datas são salvas como texto RFC3339 em UTC com nanossegundos. As casas decimais têm
+// largura fixa (RFC3339Nano corta os zeros) para manter a ordenação lexicográfica
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func parseTime(value any) time.Time {
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}
+	}
+
+	// também lê as datas salvas antes, sem fração de segundo
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Filtro das listagens paginadas: itens do nó depois do cursor, na ordem de pageOrder
+func afterCursor(node string) string {
+	return fmt.Sprintf(
+		`($after_id IS NULL OR %[1]s.created_at < $after_created_at OR (%[1]s.created_at = $after_created_at AND %[1]s.uid < $after_id))`,
+		node,
+	)
+}
+
+func pageOrder(node string) string {
+	return fmt.Sprintf(`ORDER BY %[1]s.created_at DESC, %[1]s.uid DESC LIMIT $limit`, node)
+}
+
+// Acrescenta em params os valores usados por afterCursor e pageOrder
+func withPage(params map[string]any, page repository.Page) map[string]any {
+	params["limit"] = page.Limit
+	params["after_id"] = nil
+	params["after_created_at"] = nil
+	if !page.After.IsZero() {
+		params["after_id"] = page.After.ID
+		params["after_created_at"] = formatTime(page.After.CreatedAt)
+	}
+	return params
+}
+
+func stringList(value any) []string {
+	raw, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	list := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if str, ok := item.(string); ok {
+			list = append(list, str)
+		}
+	}
+	return list
+}
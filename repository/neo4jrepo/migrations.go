@@ -0,0 +1,190 @@
+package neo4jrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"main.go/repository"
+)
+
+// Mudança de schema aplicada uma única vez. Cada query roda em sua própria transação,
+// já que o Neo4j não mistura alterações de schema e de dados na mesma
+type Migration struct {
+	Version int64
+	Name    string
+	Queries []string
+}
+
+// Em ordem de versão. Migrações já publicadas não devem ser alteradas, só acrescentadas
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "uid de usuários e posts",
+		Queries: []string{
+			`CREATE CONSTRAINT user_uid IF NOT EXISTS FOR (u:User) REQUIRE u.uid IS UNIQUE`,
+			`CREATE CONSTRAINT post_uid IF NOT EXISTS FOR (p:Post) REQUIRE p.uid IS UNIQUE`,
+			`MATCH (u:User) WHERE u.uid IS NULL SET u.uid = randomUUID()`,
+			`MATCH (p:Post) WHERE p.uid IS NULL SET p.uid = randomUUID()`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "email único",
+		Queries: []string{
+			`CREATE CONSTRAINT user_email IF NOT EXISTS FOR (u:User) REQUIRE u.email IS UNIQUE`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "índices de posts, sessões e tokens",
+		Queries: []string{
+			`CREATE INDEX post_created_at IF NOT EXISTS FOR (p:Post) ON (p.created_at)`,
+			`CREATE CONSTRAINT session_id IF NOT EXISTS FOR (s:Session) REQUIRE s.id IS UNIQUE`,
+			`CREATE INDEX session_token_hash IF NOT EXISTS FOR (s:Session) ON (s.token_hash)`,
+			`CREATE INDEX session_family IF NOT EXISTS FOR (s:Session) ON (s.family)`,
+			`CREATE INDEX password_reset_token_hash IF NOT EXISTS FOR (t:PasswordReset) ON (t.token_hash)`,
+			`CREATE INDEX email_verification_token_hash IF NOT EXISTS FOR (v:EmailVerification) ON (v.token_hash)`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "created_at de usuários para paginação",
+		Queries: []string{
+			// não há de onde tirar a data real: registros antigos recebem a época Unix e
+			// ficam no fim das listagens, ordenados só pelo uid
+			`MATCH (u:User) WHERE u.created_at IS NULL SET u.created_at = '1970-01-01T00:00:00Z'`,
+			`MATCH (p:Post) WHERE p.created_at IS NULL SET p.created_at = '1970-01-01T00:00:00Z'`,
+			`CREATE INDEX user_created_at IF NOT EXISTS FOR (u:User) ON (u.created_at)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "datas com nanossegundos",
+		Queries: []string{
+			// completa as datas salvas só com segundos para que comparem certo com as novas
+			`MATCH (n) WHERE size(n.created_at) = 20 SET n.created_at = left(n.created_at, 19) + '.000000000Z'`,
+			`MATCH (n) WHERE size(n.last_used_at) = 20 SET n.last_used_at = left(n.last_used_at, 19) + '.000000000Z'`,
+			`MATCH (n) WHERE size(n.expires_at) = 20 SET n.expires_at = left(n.expires_at, 19) + '.000000000Z'`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "papel moderator removido",
+		Queries: []string{
+			// o papel nunca deu permissão além das de um usuário comum
+			`MATCH (u:User {role: 'moderator'}) SET u.role = 'user'`,
+		},
+	},
+}
+
+// Aplica as migrações que ainda não constam como (:SchemaMigration) no banco, em ordem.
+// Antes de rodar as queries a versão é reservada com um nó sem applied_at; a constraint
+// de versão única faz uma segunda instância falhar em vez de repetir a migração.
+// Com dryRun apenas escreve em out o que seria executado, sem alterar o banco
+func Migrate(ctx context.Context, driver neo4j.DriverWithContext, database string, dryRun bool, out io.Writer) error {
+	c := client{driver: driver, database: database}
+
+	if !dryRun {
+		_, err := c.run(ctx, `CREATE CONSTRAINT schema_migration_version IF NOT EXISTS FOR (m:SchemaMigration) REQUIRE m.version IS UNIQUE`, nil)
+		if err != nil {
+			return fmt.Errorf("não foi possível criar a constraint de SchemaMigration: %v", err)
+		}
+	}
+
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range Migrations {
+		done, found := applied[migration.Version]
+		if done {
+			continue
+		}
+		pending++
+
+		if dryRun {
+			fmt.Fprintf(out, "-- migração %d: %s\n", migration.Version, migration.Name)
+			if found {
+				fmt.Fprintln(out, "-- iniciada e não concluída (outra instância aplicando ou execução interrompida)")
+			}
+			for _, query := range migration.Queries {
+				fmt.Fprintf(out, "%s;\n", query)
+			}
+			continue
+		}
+
+		if found {
+			return errMigrationInProgress(migration)
+		}
+		if err := c.applyMigration(ctx, migration); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "migração %d aplicada: %s\n", migration.Version, migration.Name)
+	}
+
+	if pending == 0 {
+		fmt.Fprintln(out, "schema atualizado, nenhuma migração pendente")
+	}
+	return nil
+}
+
+func (c client) applyMigration(ctx context.Context, migration Migration) error {
+	params := map[string]any{"version": migration.Version, "name": migration.Name, "now": formatTime(time.Now())}
+
+	_, err := c.run(ctx, `CREATE (:SchemaMigration {version: $version, name: $name, started_at: $now})`, params)
+	if errors.Is(err, repository.ErrConflict) {
+		return errMigrationInProgress(migration)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, query := range migration.Queries {
+		if _, err := c.run(ctx, query, nil); err != nil {
+			// libera a versão para a próxima tentativa
+			_, releaseErr := c.run(
+				context.WithoutCancel(ctx),
+				`MATCH (m:SchemaMigration {version: $version}) WHERE m.applied_at IS NULL DELETE m`,
+				params,
+			)
+			return errors.Join(
+				fmt.Errorf("migração %d (%s) falhou: %v", migration.Version, migration.Name, err),
+				releaseErr,
+			)
+		}
+	}
+
+	_, err = c.run(ctx, `MATCH (m:SchemaMigration {version: $version}) SET m.applied_at = $now`, params)
+	return err
+}
+
+func errMigrationInProgress(migration Migration) error {
+	return fmt.Errorf(
+		"migração %d (%s) já foi iniciada por outra instância; se nenhuma estiver migrando, apague o nó (:SchemaMigration {version: %d}) sem applied_at e rode de novo",
+		migration.Version, migration.Name, migration.Version,
+	)
+}
+
+// Versões registradas no banco: true se concluída, false se só reservada
+func (c client) appliedMigrations(ctx context.Context) (map[int64]bool, error) {
+	records, err := c.read(ctx, `MATCH (m:SchemaMigration) RETURN m.version AS version, m.applied_at IS NOT NULL AS done`, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int64]bool{}
+	for _, record := range records {
+		version, _ := record.Get("version")
+		done, _ := record.Get("done")
+		if v, ok := version.(int64); ok {
+			applied[v], _ = done.(bool)
+		}
+	}
+	return applied, nil
+}
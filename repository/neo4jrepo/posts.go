@@ -0,0 +1,199 @@
+package neo4jrepo
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"main.go/models"
+	"main.go/repository"
+)
+
+type PostRepository struct {
+	client
+}
+
+func postFromRecord(record *neo4j.Record) models.Post {
+	node, _ := record.Get("p")
+	userId, _ := record.Get("userId")
+	userName, _ := record.Get("userName")
+	userImage, _ := record.Get("profilePicture")
+
+	postNode := node.(neo4j.Node)
+	props := postNode.Props
+
+	uid, _ := props["uid"].(string)
+	ownerId, _ := userId.(string)
+	description, _ := props["description"].(string)
+	name, _ := userName.(string)
+	image, _ := userImage.(string)
+
+	return models.Post{
+		Id:          uid,
+		UserID:      ownerId,
+		UserName:    name,
+		Description: description,
+		Images:      stringList(props["images"]),
+		UserImage:   image,
+		CreatedAt:   parseTime(props["created_at"]),
+	}
+}
+
+func (r *PostRepository) list(ctx context.Context, cypher string, params map[string]any) ([]models.Post, error) {
+	records, err := r.read(ctx, cypher, params)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]models.Post, 0, len(records))
+	for _, record := range records {
+		posts = append(posts, postFromRecord(record))
+	}
+	return posts, nil
+}
+
+func (r *PostRepository) exec(ctx context.Context, cypher string, params map[string]any) error {
+	count, err := r.count(ctx, cypher, params)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Cria o post já com as imagens. Usa post.Id quando já preenchido, como em UserRepository.Create
+func (r *PostRepository) Create(ctx context.Context, userId string, post models.Post) (string, error) {
+	postId := post.Id
+	if postId == "" {
+		postId = repository.NewID()
+	}
+
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $user_id
+			CREATE (p:Post {
+				uid: $post_id,
+				description: $description,
+				images: $images,
+				created_at: $created_at
+			})
+			CREATE (u)-[:POSTED]->(p)
+			RETURN COUNT(p) AS count`,
+		map[string]any{
+			"user_id":     userId,
+			"post_id":     postId,
+			"description": post.Description,
+			"images":      post.Images,
+			"created_at":  formatTime(post.CreatedAt),
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if count == 0 {
+		return "", repository.ErrNotFound
+	}
+
+	return postId, nil
+}
+
+func (r *PostRepository) Owner(ctx context.Context, postId string) (string, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (u:User)-[:POSTED]->(p:Post)
+		 WHERE p.uid = $postId
+		 RETURN u.uid AS ownerId`,
+		map[string]any{"postId": postId},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if len(records) == 0 {
+		return "", repository.ErrNotFound
+	}
+
+	ownerId, _ := records[0].Get("ownerId")
+	return ownerId.(string), nil
+}
+
+func (r *PostRepository) Images(ctx context.Context, postId string) ([]string, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (p:Post)
+		 WHERE p.uid = $postId
+		 RETURN p.images AS images`,
+		map[string]any{"postId": postId},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	images, _ := records[0].Get("images")
+	return stringList(images), nil
+}
+
+func (r *PostRepository) Delete(ctx context.Context, postId string) error {
+	// usamos detach pois o post esta relacionado a LIKED e POSTED, apenas DELETE só funciona
+	// para nós simples sem relações
+	return r.exec(
+		ctx,
+		`MATCH (p:Post)
+		 WHERE p.uid = $postId
+		 DETACH DELETE p 
+		 RETURN COUNT(p) as count`,
+		map[string]any{"postId": postId},
+	)
+}
+
+func (r *PostRepository) List(ctx context.Context, page repository.Page) ([]models.Post, error) {
+	return r.list(
+		ctx,
+		`MATCH (u:User)-[:POSTED]->(p:Post)
+		 WHERE `+afterCursor("p")+`
+		 RETURN p, u.uid AS userId, u.name AS userName, u.image as profilePicture
+		 `+pageOrder("p"),
+		withPage(map[string]any{}, page),
+	)
+}
+
+func (r *PostRepository) ListByUser(ctx context.Context, userId string, page repository.Page) ([]models.Post, error) {
+	return r.list(
+		ctx,
+		`MATCH (u:User)-[:POSTED]->(p:Post)
+		 WHERE u.uid = $id AND `+afterCursor("p")+`
+		 RETURN p, u.uid AS userId, u.name AS userName, u.image AS profilePicture
+		 `+pageOrder("p"),
+		withPage(map[string]any{"id": userId}, page),
+	)
+}
+
+func (r *PostRepository) Like(ctx context.Context, userId string, postId string) (bool, error) {
+	return r.merge(
+		ctx,
+		`MATCH (u:User), (p:Post)
+		 WHERE u.uid = $id AND p.uid = $postId
+		 OPTIONAL MATCH (u)-[existing:LIKED]->(p)
+		 MERGE (u)-[:LIKED]->(p)
+		 RETURN existing IS NULL AS created`,
+		map[string]any{"id": userId, "postId": postId},
+	)
+}
+
+func (r *PostRepository) Unlike(ctx context.Context, userId string, postId string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User)-[r:LIKED]->(p:Post)
+		 WHERE u.uid = $id AND p.uid = $postId
+		 DELETE r
+		 RETURN COUNT(r) as count`,
+		map[string]any{"id": userId, "postId": postId},
+	)
+}
@@ -0,0 +1,187 @@
+package neo4jrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"main.go/models"
+	"main.go/repository"
+)
+
+type SessionRepository struct {
+	client
+}
+
+func sessionProps(s models.Session) map[string]any {
+	return map[string]any{
+		"id":           s.Id,
+		"family":       s.Family,
+		"token_hash":   s.TokenHash,
+		"user_agent":   s.UserAgent,
+		"ip":           s.IP,
+		"created_at":   formatTime(s.CreatedAt),
+		"last_used_at": formatTime(s.LastUsedAt),
+		"expires_at":   formatTime(s.ExpiresAt),
+		"revoked":      s.Revoked,
+		"rotated":      s.Rotated,
+	}
+}
+
+func sessionFromProps(userId string, props map[string]any) models.Session {
+	id, _ := props["id"].(string)
+	family, _ := props["family"].(string)
+	tokenHash, _ := props["token_hash"].(string)
+	userAgent, _ := props["user_agent"].(string)
+	ip, _ := props["ip"].(string)
+	revoked, _ := props["revoked"].(bool)
+	rotated, _ := props["rotated"].(bool)
+
+	return models.Session{
+		Id:         id,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  parseTime(props["created_at"]),
+		LastUsedAt: parseTime(props["last_used_at"]),
+		ExpiresAt:  parseTime(props["expires_at"]),
+		UserID:     userId,
+		Family:     family,
+		TokenHash:  tokenHash,
+		Revoked:    revoked,
+		Rotated:    rotated,
+	}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, userId string, session models.Session) error {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $userId
+		 CREATE (u)-[:HAS_SESSION]->(s:Session $props)
+		 WITH u, s
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(old:Session)
+		 WHERE old.expires_at <= $now
+		 DETACH DELETE old
+		 RETURN COUNT(DISTINCT s) AS count`,
+		map[string]any{"userId": userId, "props": sessionProps(session), "now": formatTime(session.LastUsedAt)},
+	)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (models.Session, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (u:User)-[:HAS_SESSION]->(s:Session {token_hash: $hash})
+		 RETURN u.uid AS userId, properties(s) AS props`,
+		map[string]any{"hash": tokenHash},
+	)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	if len(records) == 0 {
+		return models.Session{}, repository.ErrNotFound
+	}
+
+	userId, _ := records[0].Get("userId")
+	props, _ := records[0].Get("props")
+	return sessionFromProps(userId.(string), props.(map[string]any)), nil
+}
+
+func (r *SessionRepository) Rotate(ctx context.Context, tokenHash string, next models.Session) (bool, error) {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User)-[:HAS_SESSION]->(s:Session {token_hash: $hash})
+		 WHERE s.rotated = false AND s.revoked = false
+		 SET s.rotated = true, s.replaced_by = $props.id
+		 CREATE (u)-[:HAS_SESSION]->(:Session $props)
+		 WITH u, s
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(old:Session)
+		 WHERE old.expires_at <= $now AND old <> s
+		 DETACH DELETE old
+		 RETURN COUNT(DISTINCT s) AS count`,
+		map[string]any{"hash": tokenHash, "props": sessionProps(next), "now": formatTime(next.LastUsedAt)},
+	)
+	return count > 0, err
+}
+
+func (r *SessionRepository) RevokeFamily(ctx context.Context, family string) error {
+	_, err := r.run(
+		ctx,
+		`MATCH (s:Session {family: $family}) SET s.revoked = true`,
+		map[string]any{"family": family},
+	)
+	return err
+}
+
+func (r *SessionRepository) Active(ctx context.Context, userId string, sessionId string, now time.Time) (bool, error) {
+	count, err := countOf(r.read(
+		ctx,
+		`MATCH (u:User)-[:HAS_SESSION]->(s:Session {id: $sessionId})
+		 WHERE u.uid = $userId AND s.revoked = false AND s.expires_at > $now
+		 RETURN COUNT(s) AS count`,
+		map[string]any{"userId": userId, "sessionId": sessionId, "now": formatTime(now)},
+	))
+	return count > 0, err
+}
+
+func (r *SessionRepository) Revoke(ctx context.Context, userId string, sessionId string) error {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User)-[:HAS_SESSION]->(s:Session {id: $sessionId})
+		 WHERE u.uid = $userId
+		 MATCH (u)-[:HAS_SESSION]->(f:Session {family: s.family})
+		 SET f.revoked = true
+		 RETURN COUNT(f) AS count`,
+		map[string]any{"userId": userId, "sessionId": sessionId},
+	)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllExcept(ctx context.Context, userId string, keepSessionId string) error {
+	_, err := r.run(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(current:Session {id: $sessionId})
+		 WITH u, current.family AS family
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(s:Session)
+		 WHERE family IS NULL OR s.family <> family
+		 SET s.revoked = true`,
+		map[string]any{"id": userId, "sessionId": keepSessionId},
+	)
+	return err
+}
+
+func (r *SessionRepository) ListActive(ctx context.Context, userId string, now time.Time) ([]models.Session, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (u:User)-[:HAS_SESSION]->(s:Session)
+		 WHERE u.uid = $userId AND s.revoked = false AND s.rotated = false AND s.expires_at > $now
+		 RETURN s
+		 ORDER BY s.last_used_at DESC`,
+		map[string]any{"userId": userId, "now": formatTime(now)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(records))
+	for _, record := range records {
+		node, _ := record.Get("s")
+		sessions = append(sessions, sessionFromProps(userId, node.(neo4j.Node).Props))
+	}
+	return sessions, nil
+}
@@ -0,0 +1,425 @@
+package neo4jrepo
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"main.go/models"
+	"main.go/repository"
+)
+
+type UserRepository struct {
+	client
+}
+
+func userFromProps(id string, props map[string]any) models.User {
+	name, _ := props["name"].(string)
+	email, _ := props["email"].(string)
+	password, _ := props["password"].(string)
+	image, _ := props["image"].(string)
+	role, _ := props["role"].(string)
+	verified, _ := props["verified"].(bool)
+	pendingEmail, _ := props["pending_email"].(string)
+	totpEnabled, _ := props["totp_enabled"].(bool)
+	totpSecret, _ := props["totp_secret"].(string)
+	totpPendingSecret, _ := props["totp_pending_secret"].(string)
+	totpLastStep, _ := props["totp_last_step"].(int64)
+
+	return models.User{
+		Id:                id,
+		Name:              name,
+		Email:             email,
+		Password:          password,
+		Image:             image,
+		Role:              role,
+		CreatedAt:         parseTime(props["created_at"]),
+		Verified:          verified,
+		PendingEmail:      pendingEmail,
+		TOTPEnabled:       totpEnabled,
+		TOTPSecret:        totpSecret,
+		TOTPPendingSecret: totpPendingSecret,
+		TOTPLastStep:      totpLastStep,
+		RecoveryCodes:     stringList(props["recovery_codes"]),
+	}
+}
+
+func userFromNode(value any) models.User {
+	node := value.(neo4j.Node)
+	uid, _ := node.Props["uid"].(string)
+	return userFromProps(uid, node.Props)
+}
+
+// Primeiro usuário do resultado de run ou read, na coluna "u"
+func (r *UserRepository) single(records []*neo4j.Record, err error) (models.User, error) {
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if len(records) == 0 {
+		return models.User{}, repository.ErrNotFound
+	}
+
+	node, _ := records[0].Get("u")
+	return userFromNode(node), nil
+}
+
+func (r *UserRepository) list(ctx context.Context, cypher string, params map[string]any) ([]models.User, error) {
+	records, err := r.read(ctx, cypher, params)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, 0, len(records))
+	for _, record := range records {
+		node, _ := record.Get("u")
+		users = append(users, userFromNode(node))
+	}
+	return users, nil
+}
+
+// Para escritas que retornam "count": zero linhas afetadas vira ErrNotFound
+func (r *UserRepository) exec(ctx context.Context, cypher string, params map[string]any) error {
+	count, err := r.count(ctx, cypher, params)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// Usa user.Id quando já preenchido, para o chamador poder salvar arquivos no caminho do usuário antes
+func (r *UserRepository) Create(ctx context.Context, user models.User) (string, error) {
+	id := user.Id
+	if id == "" {
+		id = repository.NewID()
+	}
+
+	params := map[string]any{
+		"uid":        id,
+		"name":       user.Name,
+		"email":      user.Email,
+		"password":   user.Password,
+		"role":       user.Role,
+		"image":      nil,
+		"created_at": formatTime(user.CreatedAt),
+	}
+	if user.Image != "" {
+		params["image"] = user.Image
+	}
+
+	_, err := r.run(
+		ctx,
+		`CREATE (u:User {uid: $uid, name: $name, email: $email, password: $password, role: $role, image: $image, verified: false, created_at: $created_at})`,
+		params,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (models.User, error) {
+	return r.single(r.read(ctx, `MATCH (u:User) WHERE u.uid = $id RETURN u`, map[string]any{"id": id}))
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.single(r.read(ctx, `MATCH (u:User) WHERE u.email = $email RETURN u`, map[string]any{"email": email}))
+}
+
+func (r *UserRepository) List(ctx context.Context, page repository.Page) ([]models.User, error) {
+	return r.list(
+		ctx,
+		`MATCH (u:User)
+		 WHERE `+afterCursor("u")+`
+		 RETURN u
+		 `+pageOrder("u"),
+		withPage(map[string]any{}, page),
+	)
+}
+
+func (r *UserRepository) UpdateName(ctx context.Context, id string, name string) (models.User, error) {
+	return r.single(r.run(
+		ctx,
+		`MATCH (u:User) 
+		 WHERE u.uid = $id 
+		 SET u.name = $name
+		 RETURN u`,
+		map[string]any{"id": id, "name": name},
+	))
+}
+
+// Cada tipo de nó do usuário é apagado numa etapa própria: OPTIONAL MATCHes encadeados
+// multiplicariam as linhas (posts x sessões x ...) antes de apagar
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 OPTIONAL MATCH (u)-[:POSTED]->(p:Post)
+		 DETACH DELETE p
+		 WITH DISTINCT u
+		 OPTIONAL MATCH (u)-[:HAS_SESSION]->(s:Session)
+		 DETACH DELETE s
+		 WITH DISTINCT u
+		 OPTIONAL MATCH (u)-[:ATTEMPTED_LOGIN]->(a:LoginAttempt)
+		 DETACH DELETE a
+		 WITH DISTINCT u
+		 OPTIONAL MATCH (u)-[:HAS_RESET_TOKEN]->(t:PasswordReset)
+		 DETACH DELETE t
+		 WITH DISTINCT u
+		 OPTIONAL MATCH (u)-[:HAS_EMAIL_VERIFICATION]->(v:EmailVerification)
+		 DETACH DELETE v
+		 WITH DISTINCT u
+		 DETACH DELETE u
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id},
+	)
+}
+
+func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	count, err := countOf(r.read(
+		ctx,
+		`MATCH (u:User {email: $email}) RETURN COUNT(u) AS count`,
+		map[string]any{"email": email},
+	))
+	return count > 0, err
+}
+
+func (r *UserRepository) EmailTakenByOther(ctx context.Context, email string, userId string) (bool, error) {
+	count, err := countOf(r.read(
+		ctx,
+		`MATCH (u:User {email: $email}) WHERE u.uid <> $id RETURN COUNT(u) AS count`,
+		map[string]any{"email": email, "id": userId},
+	))
+	return count > 0, err
+}
+
+func (r *UserRepository) Follow(ctx context.Context, userId string, otherId string) (bool, error) {
+	return r.merge(
+		ctx,
+		`MATCH (a:User), (b:User) 
+		 WHERE a.uid = $userId AND b.uid = $otherId 
+		 OPTIONAL MATCH (a)-[existing:FOLLOWS]->(b)
+		 MERGE (a)-[:FOLLOWS]->(b)
+		 RETURN existing IS NULL AS created`,
+		map[string]any{"userId": userId, "otherId": otherId},
+	)
+}
+
+func (r *UserRepository) Unfollow(ctx context.Context, userId string, otherId string) error {
+	return r.exec(
+		ctx,
+		`MATCH (a:User)-[r:FOLLOWS]->(b:User) 
+		 WHERE a.uid = $userId AND b.uid = $otherId 
+		 DELETE r
+		 RETURN COUNT(r) as count`,
+		map[string]any{"userId": userId, "otherId": otherId},
+	)
+}
+
+func (r *UserRepository) Followers(ctx context.Context, id string, page repository.Page) ([]models.User, error) {
+	return r.list(
+		ctx,
+		`MATCH (target: User) 
+		 WHERE target.uid = $id
+		 MATCH (u:User)-[:FOLLOWS]->(target)
+		 WHERE `+afterCursor("u")+`
+		 RETURN u
+		 `+pageOrder("u"),
+		withPage(map[string]any{"id": id}, page),
+	)
+}
+
+func (r *UserRepository) Following(ctx context.Context, id string, page repository.Page) ([]models.User, error) {
+	return r.list(
+		ctx,
+		`MATCH (follower: User) 
+		 WHERE follower.uid = $id
+		 MATCH (follower)-[:FOLLOWS]->(u:User)
+		 WHERE `+afterCursor("u")+`
+		 RETURN u
+		 `+pageOrder("u"),
+		withPage(map[string]any{"id": id}, page),
+	)
+}
+
+func (r *UserRepository) Profile(ctx context.Context, id string, requesterId string) (models.User, repository.ProfileStats, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $profileId
+		 OPTIONAL MATCH (requester:User)-[:FOLLOWS]->(u)
+		 WHERE requester.uid = $requesterId
+		 OPTIONAL MATCH (u)-[:POSTED]->(p:Post)
+		 OPTIONAL MATCH (follower:User)-[:FOLLOWS]->(u)
+		 OPTIONAL MATCH (u)-[:FOLLOWS]->(followed:User)
+		 RETURN 
+			u,
+			CASE WHEN requester IS NULL THEN false ELSE true END AS isFollower, 
+		    COUNT(DISTINCT p) as postCount, 
+		    COUNT(DISTINCT follower) as totalFollowers,
+		    COUNT(DISTINCT followed) as totalFollowed`,
+		map[string]any{"profileId": id, "requesterId": requesterId},
+	)
+	if err != nil {
+		return models.User{}, repository.ProfileStats{}, err
+	}
+
+	if len(records) == 0 {
+		return models.User{}, repository.ProfileStats{}, repository.ErrNotFound
+	}
+
+	record := records[0]
+	node, _ := record.Get("u")
+	follows, _ := record.Get("isFollower")
+	postCount, _ := record.Get("postCount")
+	totalFollowers, _ := record.Get("totalFollowers")
+	totalFollowed, _ := record.Get("totalFollowed")
+
+	stats := repository.ProfileStats{
+		Follows:   follows.(bool),
+		PostCount: postCount.(int64),
+		Followers: totalFollowers.(int64),
+		Following: totalFollowed.(int64),
+	}
+
+	return userFromNode(node), stats, nil
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, id string, role string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 SET u.role = $role
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id, "role": role},
+	)
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, id string, passwordHash string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id SET u.password = $password RETURN COUNT(u) AS count`,
+		map[string]any{"id": id, "password": passwordHash},
+	)
+}
+
+func (r *UserRepository) StartTOTPEnrollment(ctx context.Context, id string, secret string) (models.User, error) {
+	return r.single(r.run(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 SET u.totp_pending_secret = CASE WHEN u.totp_enabled THEN u.totp_pending_secret ELSE $secret END
+		 RETURN u`,
+		map[string]any{"id": id, "secret": secret},
+	))
+}
+
+func (r *UserRepository) EnableTOTP(ctx context.Context, id string, secret string, step int64, recoveryHashes []string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id AND u.totp_pending_secret = $secret
+		 SET u.totp_secret = $secret,
+			 u.totp_enabled = true,
+			 u.totp_last_step = $step,
+			 u.recovery_codes = $hashes
+		 REMOVE u.totp_pending_secret
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id, "secret": secret, "step": step, "hashes": recoveryHashes},
+	)
+}
+
+func (r *UserRepository) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id AND coalesce(u.totp_last_step, -1) < $step
+		 SET u.totp_last_step = $step
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id, "step": step},
+	)
+	return count > 0, err
+}
+
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error) {
+	count, err := r.count(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id AND u.totp_enabled = true AND $hash IN u.recovery_codes
+		 SET u.recovery_codes = [c IN u.recovery_codes WHERE c <> $hash]
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id, "hash": codeHash},
+	)
+	return count > 0, err
+}
+
+func (r *UserRepository) DisableTOTP(ctx context.Context, id string) error {
+	return r.exec(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 SET u.totp_enabled = false
+		 REMOVE u.totp_secret, u.recovery_codes
+		 RETURN COUNT(u) AS count`,
+		map[string]any{"id": id},
+	)
+}
+
+func (r *UserRepository) RecordLoginAttempt(ctx context.Context, userId string, attempt models.LoginAttempt) error {
+	_, err := r.run(
+		ctx,
+		`MATCH (u:User) WHERE u.uid = $id
+		 CREATE (u)-[:ATTEMPTED_LOGIN]->(:LoginAttempt {
+			ip: $ip,
+			user_agent: $userAgent,
+			success: $success,
+			created_at: $createdAt
+		 })
+		 WITH u
+		 MATCH (u)-[:ATTEMPTED_LOGIN]->(old:LoginAttempt)
+		 WITH old
+		 ORDER BY old.created_at DESC
+		 SKIP $keep
+		 DETACH DELETE old`,
+		map[string]any{
+			"keep":      repository.LoginAttemptHistory,
+			"id":        userId,
+			"ip":        attempt.IP,
+			"userAgent": attempt.UserAgent,
+			"success":   attempt.Success,
+			"createdAt": formatTime(attempt.CreatedAt),
+		},
+	)
+	return err
+}
+
+func (r *UserRepository) LoginAttempts(ctx context.Context, userId string, limit int) ([]models.LoginAttempt, error) {
+	records, err := r.read(
+		ctx,
+		`MATCH (u:User)-[:ATTEMPTED_LOGIN]->(a:LoginAttempt)
+		 WHERE u.uid = $id
+		 RETURN a
+		 ORDER BY a.created_at DESC
+		 LIMIT $limit`,
+		map[string]any{"id": userId, "limit": limit},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := make([]models.LoginAttempt, 0, len(records))
+	for _, record := range records {
+		node, _ := record.Get("a")
+		props := node.(neo4j.Node).Props
+
+		ip, _ := props["ip"].(string)
+		userAgent, _ := props["user_agent"].(string)
+		success, _ := props["success"].(bool)
+
+		attempts = append(attempts, models.LoginAttempt{
+			IP:        ip,
+			UserAgent: userAgent,
+			Success:   success,
+			CreatedAt: parseTime(props["created_at"]),
+		})
+	}
+	return attempts, nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"main.go/models"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+)
+
+// Tentativas de login guardadas por usuário. Ao registrar uma nova, as mais antigas
+// que isso são apagadas
+const LoginAttemptHistory = 50
+
+// Identificador público (uid) de usuários e posts, gerado na criação
+func NewID() string {
+	return uuid.NewString()
+}
+
+// Posição de um item nas listagens, que são ordenadas por CreatedAt e, no empate, por ID,
+// ambos em ordem decrescente (mais recentes primeiro)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c Cursor) IsZero() bool {
+	return c.ID == ""
+}
+
+// Diz se o item (createdAt, id) vem depois do cursor na ordem das listagens
+func (c Cursor) Precedes(createdAt time.Time, id string) bool {
+	if c.IsZero() {
+		return true
+	}
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return id < c.ID
+}
+
+func UserCursor(user models.User) Cursor {
+	return Cursor{CreatedAt: user.CreatedAt, ID: user.Id}
+}
+
+func PostCursor(post models.Post) Cursor {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.Id}
+}
+
+// Até Limit itens logo depois de After. After vazio começa do mais recente
+type Page struct {
+	Limit int
+	After Cursor
+}
+
+// Contadores exibidos no perfil de um usuário
+type ProfileStats struct {
+	Follows   bool
+	PostCount int64
+	Followers int64
+	Following int64
+}
+
+// Os usuários retornados têm em Image o caminho da imagem no disco, não o conteúdo
+type UserRepository interface {
+	// gera o id com NewID se user.Id vier vazio
+	Create(ctx context.Context, user models.User) (string, error)
+	GetByID(ctx context.Context, id string) (models.User, error)
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	List(ctx context.Context, page Page) ([]models.User, error)
+	UpdateName(ctx context.Context, id string, name string) (models.User, error)
+	// remove também os posts, sessões, tentativas de login e tokens do usuário
+	Delete(ctx context.Context, id string) error
+	EmailExists(ctx context.Context, email string) (bool, error)
+	// como EmailExists, mas ignora o próprio usuário
+	EmailTakenByOther(ctx context.Context, email string, userId string) (bool, error)
+
+	// created é falso se já seguia; seguir de novo não é erro
+	Follow(ctx context.Context, userId string, otherId string) (created bool, err error)
+	Unfollow(ctx context.Context, userId string, otherId string) error
+	Followers(ctx context.Context, id string, page Page) ([]models.User, error)
+	Following(ctx context.Context, id string, page Page) ([]models.User, error)
+	Profile(ctx context.Context, id string, requesterId string) (models.User, ProfileStats, error)
+
+	SetRole(ctx context.Context, id string, role string) error
+	SetPassword(ctx context.Context, id string, passwordHash string) error
+
+	// guarda o segredo como pendente, a não ser que o 2FA já esteja ativo
+	StartTOTPEnrollment(ctx context.Context, id string, secret string) (models.User, error)
+	EnableTOTP(ctx context.Context, id string, secret string, step int64, recoveryHashes []string) error
+	// grava o passo usado; false se ele não for maior que o último aceito
+	UseTOTPStep(ctx context.Context, id string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, id string, codeHash string) (bool, error)
+	// desliga o 2FA e apaga o segredo e os códigos de recuperação
+	DisableTOTP(ctx context.Context, id string) error
+
+	// mantém só as LoginAttemptHistory mais recentes
+	RecordLoginAttempt(ctx context.Context, userId string, attempt models.LoginAttempt) error
+	LoginAttempts(ctx context.Context, userId string, limit int) ([]models.LoginAttempt, error)
+}
+
+// Os posts retornados têm em Images e UserImage os caminhos no disco, não o conteúdo
+type PostRepository interface {
+	// gera o id com NewID se post.Id vier vazio
+	Create(ctx context.Context, userId string, post models.Post) (string, error)
+	Owner(ctx context.Context, postId string) (string, error)
+	// caminhos das imagens do post, na ordem em que foram enviadas
+	Images(ctx context.Context, postId string) ([]string, error)
+	Delete(ctx context.Context, postId string) error
+	List(ctx context.Context, page Page) ([]models.Post, error)
+	ListByUser(ctx context.Context, userId string, page Page) ([]models.Post, error)
+	// created é falso se já tinha curtido
+	Like(ctx context.Context, userId string, postId string) (created bool, err error)
+	Unlike(ctx context.Context, userId string, postId string) error
+}
+
+// Create e Rotate apagam as sessões já expiradas do usuário, tomando LastUsedAt da nova
+// sessão como o momento atual. Sessões trocadas e revogadas ficam até expirar, para que
+// o reuso de um refresh token antigo continue sendo detectado
+type SessionRepository interface {
+	Create(ctx context.Context, userId string, session models.Session) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (models.Session, error)
+	// marca a sessão do token como trocada por next; false se ela já tinha sido
+	// trocada ou revogada (reuso do refresh token)
+	Rotate(ctx context.Context, tokenHash string, next models.Session) (bool, error)
+	RevokeFamily(ctx context.Context, family string) error
+	// false se a sessão não existe (ex: conta apagada), é de outro usuário, foi revogada
+	// ou expirou
+	Active(ctx context.Context, userId string, sessionId string, now time.Time) (bool, error)
+	// revoga a familia da sessão, desde que ela pertença ao usuário
+	Revoke(ctx context.Context, userId string, sessionId string) error
+	// revoga todas as sessões do usuário menos a familia de keepSessionId
+	RevokeAllExcept(ctx context.Context, userId string, keepSessionId string) error
+	ListActive(ctx context.Context, userId string, now time.Time) ([]models.Session, error)
+}
+
+// Tokens de uso único enviados por email
+type AccountTokenRepository interface {
+	// false se não existir usuário com o email. Tokens anteriores deixam de valer
+	CreatePasswordReset(ctx context.Context, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) (bool, error)
+	// troca a senha, consome o token e revoga as sessões. ErrNotFound se o token for inválido
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) error
+	// um endereço diferente do atual fica pendente no usuário até ser confirmado; o próprio
+	// endereço (cadastro) só muda verified na confirmação
+	CreateEmailVerification(ctx context.Context, userId string, email string, tokenHash string, createdAt time.Time, expiresAt time.Time) error
+	// ErrNotFound para token inválido, ErrConflict se o email passou a ser de outra conta
+	ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) error
+}
+
+// Usado pela rota de readiness para saber se o armazenamento está acessível
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+type Repositories struct {
+	Users         UserRepository
+	Posts         PostRepository
+	Sessions      SessionRepository
+	AccountTokens AccountTokenRepository
+	Health        HealthChecker
+}
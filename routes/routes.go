@@ -1,34 +1,99 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/go-chi/chi/v5"
+	"main.go/apierror"
 	"main.go/app"
+	"main.go/auth"
 	"main.go/handlers"
+	"main.go/metrics"
+	"main.go/ratelimit"
+)
+
+// Limites por rota. Rotas autenticadas contam por usuário, as públicas por IP
+var (
+	signupLimit = ratelimit.Policy{Name: "signup", Limit: 5, Period: time.Hour}
+	// cada pedido envia um email, então o limite também evita encher a caixa de alguém
+	passwordResetLimit = ratelimit.Policy{Name: "password-reset", Limit: 5, Period: time.Hour}
+	emailResendLimit   = ratelimit.Policy{Name: "email-resend", Limit: 5, Period: time.Hour}
+	postLimit          = ratelimit.Policy{Name: "post", Limit: 10, Period: time.Minute}
+	followLimit        = ratelimit.Policy{Name: "follow", Limit: 30, Period: time.Minute}
+	likeLimit          = ratelimit.Policy{Name: "like", Limit: 60, Period: time.Minute}
 )
 
 func RegisterRoutes(r chi.Router, app *app.App) {
+	requireAuth := auth.Authenticate(app.Tokens, app.Sessions)
+	limit := func(policy ratelimit.Policy) func(http.Handler) http.Handler {
+		return ratelimit.Middleware(app.RateLimiter, policy, ratelimit.ByUserOrIP)
+	}
+
+	// rotas inexistentes também respondem no formato de erro da API
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.NotFound("Route not found"))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Write(w, r, apierror.MethodNotAllowed("Method not allowed"))
+	})
+
+	// usadas pelo orquestrador, sem autenticação nem limite
+	r.Get("/healthz", handlers.HealthHandler())
+	r.Get("/readyz", handlers.ReadyHandler(app))
+	r.Get("/version", handlers.VersionHandler(app))
+	r.Handle("/metrics", metrics.Handler())
 
 	r.Route("/user", func(r chi.Router) {
-		r.Post("/", handlers.CreateUserHandler(app))
-		r.Post("/{id}/follow/{second-id}", handlers.FollowUserHandler(app))
-		r.Post("/{id}/unfollow/{second-id}", handlers.UnfollowUserHandler(app))
-		r.Post("/{id}/like/{post-id}", handlers.LikePostHandler(app))
-		r.Post("/{id}/dislike/{post-id}", handlers.DislikePostHandler(app))
+		r.With(limit(signupLimit)).Post("/", handlers.CreateUserHandler(app))
 		r.Post("/login", handlers.LoginHandler(app))
-		r.Get("/", handlers.GetAllUsersHandler(app))
-		r.Get("/{requesterId}/profile/{id}", handlers.GetProfileHandler(app))
-		r.Get("/{id}", handlers.GetUserByIdHandler(app))
-		r.Get("/{id}/followers", handlers.GetFollowersHandler(app))
-		r.Get("/{id}/following", handlers.GetFollowingHandler(app))
-		r.Get("/email/{email}", handlers.GetUserByEmailHandler(app))
-		r.Put("/", handlers.UpdateUserHandler(app))
-		r.Delete("/{id}", handlers.DeleteUserHandler(app))
+		r.Post("/login/2fa", handlers.LoginTwoFactorHandler(app))
+		r.Post("/token/refresh", handlers.RefreshTokenHandler(app))
+		r.With(limit(passwordResetLimit)).Post("/password/forgot", handlers.ForgotPasswordHandler(app))
+		r.Post("/password/reset", handlers.ResetPasswordHandler(app))
+		r.Post("/email/confirm", handlers.ConfirmEmailHandler(app))
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.OptionalAuthenticate(app.Tokens, app.Sessions))
+			r.Get("/", handlers.GetAllUsersHandler(app))
+			r.Get("/{id}", handlers.GetUserByIdHandler(app))
+			r.Get("/{id}/followers", handlers.GetFollowersHandler(app))
+			r.Get("/{id}/following", handlers.GetFollowingHandler(app))
+			r.Get("/{id}/image", handlers.UserImageHandler(app))
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAuth)
+			r.Post("/logout", handlers.LogoutHandler(app))
+			r.Put("/password", handlers.ChangePasswordHandler(app))
+			r.Post("/2fa/enroll", handlers.EnrollTwoFactorHandler(app))
+			r.Post("/2fa/confirm", handlers.ConfirmTwoFactorHandler(app))
+			r.Post("/2fa/disable", handlers.DisableTwoFactorHandler(app))
+			r.Get("/sessions", handlers.GetSessionsHandler(app))
+			r.Get("/login-attempts", handlers.GetLoginAttemptsHandler(app))
+			r.Delete("/sessions/{id}", handlers.DeleteSessionHandler(app))
+			r.With(limit(followLimit)).Post("/follow/{id}", handlers.FollowUserHandler(app))
+			r.With(limit(followLimit)).Post("/unfollow/{id}", handlers.UnfollowUserHandler(app))
+			r.With(limit(likeLimit)).Post("/like/{post-id}", handlers.LikePostHandler(app))
+			r.With(limit(likeLimit)).Post("/dislike/{post-id}", handlers.DislikePostHandler(app))
+			r.Get("/profile/{id}", handlers.GetProfileHandler(app))
+			r.Get("/email/{email}", handlers.GetUserByEmailHandler(app))
+			r.With(limit(emailResendLimit)).Post("/email/resend", handlers.ResendEmailVerificationHandler(app))
+			r.Put("/", handlers.UpdateUserHandler(app))
+			r.Delete("/{id}", handlers.DeleteUserHandler(app))
+			r.Put("/{id}/role", handlers.ChangeRoleHandler(app))
+		})
 	})
 
 	r.Route("/posts", func(r chi.Router) {
-		r.Post("/", handlers.CreatePostHandler(app))
 		r.Get("/", handlers.GetAllPostsHandler(app))
 		r.Get("/{id}", handlers.GetPostsFromUserHandler(app))
-		r.Delete("/{post-id}/user/{id}", handlers.DeletePostHandler(app))
+		r.Get("/{post-id}/images/{index}", handlers.PostImageHandler(app))
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAuth)
+			r.With(limit(postLimit)).Post("/", handlers.CreatePostHandler(app))
+			r.Delete("/{post-id}", handlers.DeletePostHandler(app))
+		})
 	})
 }
@@ -0,0 +1,124 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"main.go/config"
+	"main.go/tracing"
+)
+
+// Troca o provider global por um que guarda os spans em memória
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	if _, err := tracing.Init(config.TracingConfig{Exporter: "none"}); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(t.Context())
+	})
+
+	return exporter
+}
+
+func TestMiddlewareNamesSpanByRouteAndContinuesTraceparent(t *testing.T) {
+	exporter := recordSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "neo4j.query")
+		tracing.End(span, nil)
+	})
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	query, server := spans[0], spans[1]
+
+	if server.Name != "GET /user/{id}" {
+		t.Errorf("span name = %q, want %q", server.Name, "GET /user/{id}")
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", server.SpanKind)
+	}
+	assertAttribute(t, server, semconv.HTTPRoute("/user/{id}"))
+	assertAttribute(t, server, semconv.HTTPResponseStatusCode(http.StatusOK))
+
+	wantTrace, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	wantParent, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if got := server.SpanContext.TraceID(); got != wantTrace {
+		t.Errorf("trace id = %s, want %s from traceparent", got, wantTrace)
+	}
+	if got := server.Parent.SpanID(); got != wantParent || !server.Parent.IsRemote() {
+		t.Errorf("parent = %s (remote %v), want remote %s", got, server.Parent.IsRemote(), wantParent)
+	}
+
+	if query.Parent.SpanID() != server.SpanContext.SpanID() || query.SpanContext.TraceID() != wantTrace {
+		t.Errorf("handler span is not a child of the request span")
+	}
+}
+
+func TestMiddlewareWithoutTraceparentStartsNewTrace(t *testing.T) {
+	exporter := recordSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Get("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/7", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /posts/{id}" {
+		t.Errorf("span name = %q, want %q", span.Name, "GET /posts/{id}")
+	}
+	if span.Parent.IsValid() {
+		t.Errorf("span has parent %s without traceparent", span.Parent.SpanID())
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("status = %v, want Error for a 500", span.Status.Code)
+	}
+}
+
+func assertAttribute(t *testing.T, span tracetest.SpanStub, want attribute.KeyValue) {
+	t.Helper()
+
+	for _, attr := range span.Attributes {
+		if attr.Key == want.Key {
+			if attr.Value.Emit() != want.Value.Emit() {
+				t.Errorf("%s = %v, want %v", want.Key, attr.Value.Emit(), want.Value.Emit())
+			}
+			return
+		}
+	}
+	t.Errorf("span without attribute %s", want.Key)
+}
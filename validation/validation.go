@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"main.go/apierror"
+	"main.go/auth"
+)
+
+const (
+	NameMaxLength        = 100
+	EmailMaxLength       = 254
+	DescriptionMaxLength = 2000
+)
+
+// Problema em um campo da requisição, enviado em details
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Junta os problemas de todos os campos, para o cliente corrigir tudo de uma vez.
+// Cada regra é ignorada se o campo já tiver um erro
+type Validator struct {
+	errors []FieldError
+}
+
+func (v *Validator) Add(field string, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+func (v *Validator) failed(field string) bool {
+	for _, err := range v.errors {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) Check(ok bool, field string, message string) {
+	if !ok && !v.failed(field) {
+		v.Add(field, message)
+	}
+}
+
+func (v *Validator) Required(field string, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+func (v *Validator) MaxLength(field string, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters", max))
+}
+
+// Nome obrigatório com até NameMaxLength caracteres
+func (v *Validator) Name(field string, value string) {
+	v.Required(field, value)
+	v.MaxLength(field, value, NameMaxLength)
+}
+
+// Só o endereço, sem nome (ex: "Ana <ana@x.com>" não vale)
+func (v *Validator) Email(field string, value string) {
+	v.Required(field, value)
+	v.MaxLength(field, value, EmailMaxLength)
+
+	address, err := mail.ParseAddress(value)
+	v.Check(err == nil && address.Address == value, field, "must be a valid email address")
+}
+
+func (v *Validator) Password(field string, value string, policy auth.PasswordPolicy) {
+	v.Required(field, value)
+
+	problems := policy.Problems(value)
+	v.Check(len(problems) == 0, field, "must have "+strings.Join(problems, ", "))
+}
+
+func (v *Validator) Image(field string, file *multipart.FileHeader, maxSize int64) {
+	v.Check(file.Size <= maxSize, field, fmt.Sprintf("must be at most %d bytes", maxSize))
+}
+
+// Até max imagens de até maxSize bytes cada. Os erros de tamanho apontam o arquivo, ex: images[2]
+func (v *Validator) Images(field string, files []*multipart.FileHeader, max int, maxSize int64) {
+	v.Check(len(files) <= max, field, fmt.Sprintf("must have at most %d images", max))
+
+	for i, file := range files {
+		v.Image(fmt.Sprintf("%s[%d]", field, i), file, maxSize)
+	}
+}
+
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Erro 422 com os campos inválidos em details, ou nil se não houver nenhum
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return apierror.Validation("Invalid fields", v.errors)
+}
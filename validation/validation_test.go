@@ -0,0 +1,189 @@
+package validation_test
+
+import (
+	"errors"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"main.go/apierror"
+	"main.go/auth"
+	"main.go/validation"
+)
+
+// Campos com erro em v, na ordem em que foram adicionados
+func invalidFields(t *testing.T, v *validation.Validator) []string {
+	t.Helper()
+
+	err := v.Err()
+	if err == nil {
+		return nil
+	}
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidation {
+		t.Fatalf("Err() = %v, want a validation error", err)
+	}
+
+	var fields []string
+	for _, fieldErr := range apiErr.Details.([]validation.FieldError) {
+		fields = append(fields, fieldErr.Field)
+	}
+	return fields
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		valid bool
+	}{
+		{"plain address", "ana@example.com", true},
+		{"subdomain", "ana.silva@mail.example.com.br", true},
+		{"empty", "", false},
+		{"blank", "   ", false},
+		{"missing at", "ana.example.com", false},
+		{"missing domain", "ana@", false},
+		{"display name", "Ana <ana@example.com>", false},
+		{"surrounding spaces", " ana@example.com ", false},
+		{"too long", strings.Repeat("a", validation.EmailMaxLength) + "@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validation.Validator
+			v.Email("email", tt.email)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("Email(%q) valid = %t, want %t", tt.email, v.Valid(), tt.valid)
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"short", "Ana", true},
+		{"at the limit", strings.Repeat("a", validation.NameMaxLength), true},
+		// o limite é em caracteres, não em bytes
+		{"multibyte at the limit", strings.Repeat("é", validation.NameMaxLength), true},
+		{"empty", "", false},
+		{"blank", " \t ", false},
+		{"over the limit", strings.Repeat("a", validation.NameMaxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validation.Validator
+			v.Name("name", tt.value)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("Name(%d chars) valid = %t, want %t", len([]rune(tt.value)), v.Valid(), tt.valid)
+			}
+		})
+	}
+}
+
+func TestPassword(t *testing.T) {
+	policy := auth.PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"strong", "Passw0rd!Long", true},
+		{"empty", "", false},
+		{"too short", "Pa0!", false},
+		{"no upper", "passw0rd!long", false},
+		{"no lower", "PASSW0RD!LONG", false},
+		{"no digit", "Password!Long", false},
+		{"no symbol", "Passw0rdLong1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validation.Validator
+			v.Password("password", tt.password, policy)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("Password(%q) valid = %t, want %t", tt.password, v.Valid(), tt.valid)
+			}
+		})
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"empty", "", true},
+		{"at the limit", strings.Repeat("a", validation.DescriptionMaxLength), true},
+		{"over the limit", strings.Repeat("a", validation.DescriptionMaxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validation.Validator
+			v.MaxLength("description", tt.value, validation.DescriptionMaxLength)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("MaxLength(%d chars) valid = %t, want %t", len(tt.value), v.Valid(), tt.valid)
+			}
+		})
+	}
+}
+
+func TestImages(t *testing.T) {
+	const maxImages, maxSize = 2, 100
+
+	image := func(size int64) *multipart.FileHeader {
+		return &multipart.FileHeader{Filename: "image.jpg", Size: size}
+	}
+
+	tests := []struct {
+		name   string
+		images []*multipart.FileHeader
+		want   []string
+	}{
+		{"none", nil, nil},
+		{"at the limits", []*multipart.FileHeader{image(maxSize), image(1)}, nil},
+		{"too many", []*multipart.FileHeader{image(1), image(1), image(1)}, []string{"images"}},
+		{"too large", []*multipart.FileHeader{image(1), image(maxSize + 1)}, []string{"images[1]"}},
+		{"too many and too large", []*multipart.FileHeader{image(maxSize + 1), image(1), image(maxSize + 1)}, []string{"images", "images[0]", "images[2]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v validation.Validator
+			v.Images("images", tt.images, maxImages, maxSize)
+
+			if got := invalidFields(t, &v); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("invalid fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Cada campo aparece uma vez, com a primeira regra que falhou
+func TestValidatorReportsEachFieldOnce(t *testing.T) {
+	var v validation.Validator
+	v.Name("name", "")
+	v.Email("email", "")
+	v.Required("bio", "ok")
+
+	if got := invalidFields(t, &v); strings.Join(got, ",") != "name,email" {
+		t.Errorf("invalid fields = %v, want [name email]", got)
+	}
+
+	var fine validation.Validator
+	fine.Name("name", "Ana")
+	if err := fine.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}